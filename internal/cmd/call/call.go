@@ -1,55 +1,142 @@
 package call
 
 import (
+	"bytes"
 	"context"
+	"errors"
 	"fmt"
 
 	"io"
 	"log"
+	"log/slog"
 	"os"
+	"path/filepath"
+	"strings"
+	"time"
 
 	"github.com/lucasepe/resto/internal/restclient"
 	getoptutil "github.com/lucasepe/resto/internal/util/getopt"
 	ioutil "github.com/lucasepe/resto/internal/util/io"
+	"github.com/lucasepe/resto/internal/util/redact"
 	"github.com/lucasepe/resto/internal/util/retry"
+	yamlutil "github.com/lucasepe/resto/internal/util/yaml"
 	"github.com/lucasepe/x/getopt"
 	"github.com/lucasepe/x/text/conv"
 )
 
-func Do(args []string) error {
+// UsageError reports a command line that cannot be understood,
+// like an unknown flag, a missing argument or an invalid value.
+type UsageError struct {
+	Err error
+}
+
+func (e *UsageError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *UsageError) Unwrap() error {
+	return e.Err
+}
+
+// TimeoutError is returned when the --timeout deadline expires.
+type TimeoutError struct {
+	Timeout time.Duration
+}
+
+func (e *TimeoutError) Error() string {
+	return fmt.Sprintf("timeout of %s exceeded", e.Timeout)
+}
+
+func (e *TimeoutError) Unwrap() error {
+	return context.DeadlineExceeded
+}
+
+// ClientFlags are the long options, shared with the other commands,
+// that configure the HTTP client and the retry behaviour.
+var ClientFlags = []string{
+	"backoff=",
+	"backoff-multiplier=",
+	"ca-cert=",
+	"ca-file=",
+	"cert=",
+	"cert-file=",
+	"cert-key=",
+	"context=",
+	"initial-delay=",
+	"insecure",
+	"key-file=",
+	"kubeconfig=",
+	"log-format=",
+	"max-attempts=",
+	"max-delay=",
+	"max-jitter=",
+	"oauth-audience=",
+	"oauth-client-id=",
+	"oauth-client-secret=",
+	"oauth-scopes=",
+	"oauth-token-url=",
+	"password=",
+	"proxy-url",
+	"record=",
+	"redact-field=",
+	"redact-header=",
+	"replay=",
+	"request-timeout=",
+	"retry-on=",
+	"show-secrets",
+	"system-ca",
+	"timeout=",
+	"token=",
+	"token-command=",
+	"token-command-ttl=",
+	"token-file=",
+	"username=",
+	"verbose",
+}
+
+func Do(ctx context.Context, args []string) (err error) {
 	extras, opts, err := getopt.GetOpt(args,
-		"X:H:f:v",
-		[]string{
-			"proxy-url",
-			"max-attempts=",
-			"max-delay=",
-			"ca-cert=",
-			"cert=",
-			"cert-key=",
+		"X:H:f:o:q:ru:v",
+		append([]string{
+			"async",
+			"fail-if=",
 			"file=",
 			"header=",
-			"insecure",
-			"initial-delay=",
-			"max-jitter=",
-			"password=",
+			"jq=",
+			"merge",
+			"next=",
+			"next-param=",
+			"output=",
+			"paginate",
+			"poll-url=",
+			"query=",
+			"raw-output",
 			"request=",
-			"token=",
+			"strict-vars",
+			"stream",
+			"template",
 			"until=",
-			"username=",
-			"verbose",
-		},
+			"var=",
+			"watch",
+			"yaml",
+		}, ClientFlags...),
 	)
 	if err != nil {
-		return err
+		return &UsageError{Err: err}
 	}
 
 	if len(extras) < 1 {
-		return fmt.Errorf("missing request uri")
+		return &UsageError{Err: fmt.Errorf("missing request uri")}
 	}
 
-	reqOpts, err := requestOptions(extras, opts)
+	tpl, err := templateOptionsFrom(opts)
 	if err != nil {
-		return err
+		return &UsageError{Err: err}
+	}
+
+	reqOpts, err := requestOptions(extras, opts, tpl)
+	if err != nil {
+		return &UsageError{Err: err}
 	}
 
 	streams := ioStreams(opts)
@@ -60,34 +147,174 @@ func Do(args []string) error {
 	}
 	defer close()
 
-	expr := getoptutil.EnvOrOptVal("UNTIL", opts, []string{"--until"})
+	if tpl.enabled && streams.In != nil {
+		body, err := io.ReadAll(streams.In)
+		if err != nil {
+			return err
+		}
+
+		res, err := tpl.expand(string(body))
+		if err != nil {
+			return &UsageError{Err: err}
+		}
+		streams.In = strings.NewReader(res)
+	}
+
+	bodies := []io.Reader{streams.In}
+	if streams.In != nil && wantsYAML(opts, filename) {
+		bodies, err = yamlBodies(streams.In)
+		if err != nil {
+			return err
+		}
+
+		// user defined headers come later and can override it
+		reqOpts.Headers = append([]string{"Content-Type: application/json"}, reqOpts.Headers...)
+	}
+
+	expr := getoptutil.EnvOrOptVal("UNTIL", opts, []string{"-u", "--until"})
+	failIf := getoptutil.EnvOrOptVal("FAIL_IF", opts, []string{"--fail-if"})
+	reqOpts.Until, reqOpts.FailIf = expr, failIf
+	if reqOpts.PollURL != "" && expr == "" {
+		return &UsageError{Err: fmt.Errorf("--poll-url requires an --until expression")}
+	}
+
+	if reqOpts.Async && expr != "" {
+		return &UsageError{Err: fmt.Errorf("--async cannot be used with --until")}
+	}
+
+	cfg, err := ClientConfig(opts)
+	if err != nil {
+		return err
+	}
+
+	saveHAR, err := HARTransport(opts, &cfg)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if serr := saveHAR(); serr != nil && err == nil {
+			err = serr
+		}
+	}()
 
-	cfg := restClientConfig(opts)
 	if reqOpts.BaseURL == "" {
 		reqOpts.BaseURL = cfg.ServerURL
 	}
 
+	logger, err := Logger(opts, cfg.Verbose)
+	if err != nil {
+		return err
+	}
+	cfg.Logger = logger
+	cfg.Stream = reqOpts.Stream
+
 	if cfg.Verbose && expr != "" {
-		log.Printf("jq expression: %q\n", expr)
+		debugf(logger, "jq expression: %q", expr)
+	}
+
+	if cfg.Verbose && failIf != "" {
+		debugf(logger, "jq fail expression: %q", failIf)
+	}
+
+	retryOpts := RetryOptions(opts)
+
+	retryOn, err := RetryOn(opts)
+	if err != nil {
+		return err
 	}
 
-	retryOpts := retryOptions(opts)
+	reqOpts.Reconnect = restclient.ReconnectOptions{
+		MaxAttempts: retryOpts.MaxAttempts,
+		Delay:       retryOpts.InitialDelay,
+		MaxDelay:    retryOpts.MaxDelay,
+		Clock:       retryOpts.Clock,
+	}
+
+	// the strategies keep state, the two loops need their own
+	strategy, err := Strategy(retryOpts)
+	if err != nil {
+		return err
+	}
+
+	opStrategy, err := Strategy(retryOpts)
+	if err != nil {
+		return err
+	}
+
+	reqOpts.Operation = restclient.OperationOptions{
+		Retrier:  retry.NewRetrier(retryOpts),
+		Strategy: opStrategy,
+		Clock:    retryOpts.Clock,
+	}
 
 	cli, err := restclient.HTTPClientForConfig(cfg)
 	if err != nil {
 		return err
 	}
-	cli.Transport = retry.NewRoundTripperWithEval(cli.Transport, expr,
-		retry.Jittered(retryOpts.MaxJitter),
-		retry.NewRetrier(retryOpts),
-	)
+	cli.Transport = retry.NewRoundTripper(cli.Transport, retry.RoundTripperOptions{
+		Until:          expr,
+		FailIf:         failIf,
+		RetryOn:        retryOn,
+		RequestTimeout: retryOpts.RequestTimeout,
+		Stream:         reqOpts.Stream,
+		Logger:         logger,
+		Clock:          retryOpts.Clock,
+		Strategy:       strategy,
+		Retrier:        retry.NewRetrier(retryOpts),
+	})
+
+	if retryOpts.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, retryOpts.Timeout)
+		defer cancel()
+	}
+
+	rc := restclient.New(reqOpts)
+	for _, body := range bodies {
+		streams.In = body
+		if err = rc.Do(ctx, cli, streams); err != nil {
+			break
+		}
+	}
+
+	if err != nil && errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return &TimeoutError{Timeout: retryOpts.Timeout}
+	}
 
-	return restclient.New(reqOpts).Do(context.Background(), cli, streams)
+	WriteFailBody(streams.Err, err)
+
+	return err
+}
+
+// WriteFailBody prints to wri the response that made the
+// fail-if expression true, if err is a *retry.FailError.
+func WriteFailBody(wri io.Writer, err error) {
+	var failErr *retry.FailError
+	if errors.As(err, &failErr) && wri != nil {
+		fmt.Fprintln(wri, string(failErr.Body))
+	}
 }
 
-func restClientConfig(opts []getopt.OptArg) restclient.Config {
+// ClientConfig returns the REST client configuration from the environment,
+// the optional kubeconfig and the command line flags, in increasing priority.
+func ClientConfig(opts []getopt.OptArg) (restclient.Config, error) {
 	cfg := restclient.ConfigFromEnv()
 
+	kubeconfig := getoptutil.OptVal(opts, []string{"--kubeconfig"})
+	kubecontext := getoptutil.OptVal(opts, []string{"--context"})
+	if kubeconfig != "" || kubecontext != "" {
+		if kubeconfig == "" {
+			kubeconfig = restclient.DefaultKubeconfig()
+		}
+
+		kc, err := restclient.ConfigFromKubeconfig(kubeconfig, kubecontext)
+		if err != nil {
+			return cfg, err
+		}
+		kc.Verbose = cfg.Verbose
+		cfg = kc
+	}
+
 	proxyUrl := getoptutil.OptVal(opts, []string{"--proxy-url"})
 	if proxyUrl != "" {
 		cfg.ProxyURL = proxyUrl
@@ -100,9 +327,44 @@ func restClientConfig(opts []getopt.OptArg) restclient.Config {
 		cfg.Password = password
 	}
 
+	// a token given on the command line replaces any other
 	token := getoptutil.OptVal(opts, []string{"--token"})
 	if token != "" {
-		cfg.Token = token
+		cfg.Token, cfg.TokenFile, cfg.TokenCommand = token, "", ""
+	}
+
+	tokenFile := getoptutil.OptVal(opts, []string{"--token-file"})
+	if tokenFile != "" {
+		cfg.Token, cfg.TokenFile, cfg.TokenCommand = "", tokenFile, ""
+	}
+
+	tokenCommand := getoptutil.OptVal(opts, []string{"--token-command"})
+	if tokenCommand != "" {
+		cfg.Token, cfg.TokenFile, cfg.TokenCommand = "", "", tokenCommand
+	}
+
+	if val := getoptutil.OptVal(opts, []string{"--token-command-ttl"}); val != "" {
+		cfg.TokenCommandTTL = conv.Duration(val, cfg.TokenCommandTTL)
+	}
+
+	if v := getoptutil.OptVal(opts, []string{"--oauth-token-url"}); v != "" {
+		cfg.OAuthTokenURL = v
+	}
+
+	if v := getoptutil.OptVal(opts, []string{"--oauth-client-id"}); v != "" {
+		cfg.OAuthClientID = v
+	}
+
+	if v := getoptutil.OptVal(opts, []string{"--oauth-client-secret"}); v != "" {
+		cfg.OAuthClientSecret = v
+	}
+
+	if v := getoptutil.OptVal(opts, []string{"--oauth-scopes"}); v != "" {
+		cfg.OAuthScopes = v
+	}
+
+	if v := getoptutil.OptVal(opts, []string{"--oauth-audience"}); v != "" {
+		cfg.OAuthAudience = v
 	}
 
 	clientCert := getoptutil.OptVal(opts, []string{"--cert"})
@@ -120,13 +382,53 @@ func restClientConfig(opts []getopt.OptArg) restclient.Config {
 		cfg.CertificateAuthorityData = caCert
 	}
 
-	cfg.Insecure = getoptutil.HasOpt(opts, []string{"--insecure"})
-	cfg.Verbose = getoptutil.HasOpt(opts, []string{"-v", "--verbose"})
+	// a file given on the command line replaces the inline data
+	if v := getoptutil.OptVal(opts, []string{"--cert-file"}); v != "" {
+		cfg.ClientCertificateData, cfg.ClientCertificateFile = "", v
+	}
+
+	if v := getoptutil.OptVal(opts, []string{"--key-file"}); v != "" {
+		cfg.ClientKeyData, cfg.ClientKeyFile = "", v
+	}
+
+	if v := getoptutil.OptVal(opts, []string{"--ca-file"}); v != "" {
+		cfg.CertificateAuthorityData, cfg.CertificateAuthorityFile = "", v
+	}
 
-	return cfg
+	if getoptutil.HasOpt(opts, []string{"--system-ca"}) {
+		cfg.SystemRoots = true
+	}
+
+	if getoptutil.HasOpt(opts, []string{"--insecure"}) {
+		cfg.Insecure = true
+	}
+
+	if getoptutil.HasOpt(opts, []string{"-v", "--verbose"}) {
+		cfg.Verbose = true
+	}
+
+	if !getoptutil.HasOpt(opts, []string{"--show-secrets"}) {
+		cfg.Redactor = redact.New(
+			splitList(getoptutil.AllOptArgs(opts, []string{"--redact-header"})),
+			splitList(getoptutil.AllOptArgs(opts, []string{"--redact-field"})),
+		)
+	}
+
+	return cfg, nil
+}
+
+// splitList splits the comma separated values of a repeatable flag.
+func splitList(values []string) []string {
+	var res []string
+	for _, el := range values {
+		res = append(res, strings.Split(el, ",")...)
+	}
+	return res
 }
 
-func retryOptions(opts []getopt.OptArg) retry.RetryOptions {
+// RetryOptions returns the retrier options from the environment
+// overridden by the command line flags.
+func RetryOptions(opts []getopt.OptArg) retry.RetryOptions {
 	res := retry.OptionsFromEnv()
 
 	val := getoptutil.OptVal(opts, []string{"--initial-delay"})
@@ -149,24 +451,179 @@ func retryOptions(opts []getopt.OptArg) retry.RetryOptions {
 		res.MaxJitter = conv.Duration(val, res.MaxJitter)
 	}
 
+	val = getoptutil.OptVal(opts, []string{"--timeout"})
+	if val != "" {
+		res.Timeout = conv.Duration(val, res.Timeout)
+	}
+
+	val = getoptutil.OptVal(opts, []string{"--request-timeout"})
+	if val != "" {
+		res.RequestTimeout = conv.Duration(val, res.RequestTimeout)
+	}
+
+	val = getoptutil.OptVal(opts, []string{"--backoff"})
+	if val != "" {
+		res.Backoff = val
+	}
+
+	val = getoptutil.OptVal(opts, []string{"--backoff-multiplier"})
+	if val != "" {
+		res.Multiplier = conv.Float64(val, res.Multiplier)
+	}
+
 	return res
 }
 
-func requestOptions(extras []string, opts []getopt.OptArg) (restclient.RequestOptions, error) {
-	baseURL, path, params, err := reverseURL(extras[0])
+// Strategy returns the back-off strategy set with --backoff or BACKOFF.
+func Strategy(opts retry.RetryOptions) (retry.Strategy, error) {
+	res, err := retry.NewStrategy(opts)
+	if err != nil {
+		return nil, &UsageError{Err: err}
+	}
+
+	return res, nil
+}
+
+// RetryOn returns the retryable conditions set with --retry-on or RETRY_ON.
+func RetryOn(opts []getopt.OptArg) (retry.RetryOn, error) {
+	res, err := retry.ParseRetryOn(
+		getoptutil.EnvOrOptVal("RETRY_ON", opts, []string{"--retry-on"}))
+	if err != nil {
+		return res, &UsageError{Err: err}
+	}
+
+	return res, nil
+}
+
+// Logger returns the structured logger set with --log-format or
+// LOG_FORMAT (text or json), writing to stderr at Info level or at
+// Debug level when verbose; nil if no format is set.
+func Logger(opts []getopt.OptArg, verbose bool) (*slog.Logger, error) {
+	format := getoptutil.EnvOrOptVal("LOG_FORMAT", opts, []string{"--log-format"})
+
+	ho := &slog.HandlerOptions{Level: slog.LevelInfo}
+	if verbose {
+		ho.Level = slog.LevelDebug
+	}
+
+	switch strings.ToLower(strings.TrimSpace(format)) {
+	case "":
+		return nil, nil
+	case "json":
+		return slog.New(slog.NewJSONHandler(os.Stderr, ho)), nil
+	case "text":
+		return slog.New(slog.NewTextHandler(os.Stderr, ho)), nil
+	default:
+		return nil, &UsageError{Err: fmt.Errorf("unsupported log format %q (use text or json)", format)}
+	}
+}
+
+// debugf writes the message as a debug record when a logger is set,
+// with the standard logger otherwise.
+func debugf(logger *slog.Logger, format string, args ...any) {
+	if logger != nil {
+		logger.Debug(fmt.Sprintf(format, args...))
+		return
+	}
+	log.Printf(format+"\n", args...)
+}
+
+func requestOptions(extras []string, opts []getopt.OptArg, tpl templateOptions) (restclient.RequestOptions, error) {
+	uri, err := tpl.expand(extras[0])
 	if err != nil {
 		return restclient.RequestOptions{}, err
 	}
 
+	baseURL, path, params, err := reverseURL(uri)
+	if err != nil {
+		return restclient.RequestOptions{}, err
+	}
+
+	output, err := restclient.ParseOutputFormat(
+		getoptutil.EnvOrOptVal("OUTPUT", opts, []string{"-o", "--output"}))
+	if err != nil {
+		return restclient.RequestOptions{}, err
+	}
+
+	headers := getoptutil.AllOptArgs(opts, []string{"-H", "--header"})
+	for i, el := range headers {
+		headers[i], err = tpl.expand(el)
+		if err != nil {
+			return restclient.RequestOptions{}, err
+		}
+	}
+
+	pollURL, err := tpl.expand(getoptutil.OptVal(opts, []string{"--poll-url"}))
+	if err != nil {
+		return restclient.RequestOptions{}, err
+	}
+
+	nextExpr := getoptutil.OptVal(opts, []string{"--next"})
+	nextParam := getoptutil.OptVal(opts, []string{"--next-param"})
+	if nextParam != "" && nextExpr == "" {
+		return restclient.RequestOptions{}, fmt.Errorf("--next-param requires a --next expression")
+	}
+	merge := getoptutil.HasOpt(opts, []string{"--merge"})
+
 	return restclient.RequestOptions{
-		BaseURL: baseURL,
-		Method:  getoptutil.OptVal(opts, []string{"-X", "--request"}),
-		Path:    path,
-		Headers: getoptutil.AllOptArgs(opts, []string{"-H", "--header"}),
-		Params:  params,
+		BaseURL:   baseURL,
+		Method:    getoptutil.OptVal(opts, []string{"-X", "--request"}),
+		Path:      path,
+		Headers:   headers,
+		Params:    params,
+		Query:     getoptutil.OptVal(opts, []string{"-q", "--query", "--jq"}),
+		RawOutput: getoptutil.HasOpt(opts, []string{"-r", "--raw-output"}),
+		Output:    output,
+		Stream:    getoptutil.HasOpt(opts, []string{"--watch", "--stream"}),
+		Paginate:  merge || nextExpr != "" || getoptutil.HasOpt(opts, []string{"--paginate"}),
+		NextExpr:  nextExpr,
+		NextParam: nextParam,
+		Merge:     merge,
+		PollURL:   pollURL,
+		Async:     getoptutil.HasOpt(opts, []string{"--async"}),
 	}, nil
 }
 
+// wantsYAML reports whether the request body must be converted
+// from YAML to JSON: explicitly with --yaml or by file extension.
+func wantsYAML(opts []getopt.OptArg, filename string) bool {
+	if getoptutil.HasOpt(opts, []string{"--yaml"}) {
+		return true
+	}
+
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".yaml", ".yml":
+		return true
+	default:
+		return false
+	}
+}
+
+// yamlBodies converts the YAML source into one JSON request body
+// for each YAML document.
+func yamlBodies(in io.Reader) ([]io.Reader, error) {
+	src, err := io.ReadAll(in)
+	if err != nil {
+		return nil, err
+	}
+
+	docs, err := yamlutil.ToJSON(src)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(docs) == 0 {
+		return nil, fmt.Errorf("no YAML documents found in request body")
+	}
+
+	res := make([]io.Reader, len(docs))
+	for i, el := range docs {
+		res[i] = bytes.NewReader(el)
+	}
+
+	return res, nil
+}
+
 func ioStreams(opts []getopt.OptArg) restclient.IOStreams {
 	ios := restclient.IOStreams{
 		Out: os.Stdout,
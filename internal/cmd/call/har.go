@@ -0,0 +1,57 @@
+package call
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/lucasepe/resto/internal/har"
+	"github.com/lucasepe/resto/internal/restclient"
+	getoptutil "github.com/lucasepe/resto/internal/util/getopt"
+	"github.com/lucasepe/x/getopt"
+)
+
+// HARTransport configures the client for --record and --replay.
+//
+// With --record every attempt is captured and the returned function
+// writes the HAR file: call it once the requests are done, whatever
+// their outcome. With --replay the responses are served from the HAR
+// file and nothing is sent over the network.
+func HARTransport(opts []getopt.OptArg, cfg *restclient.Config) (func() error, error) {
+	record := getoptutil.OptVal(opts, []string{"--record"})
+	replay := getoptutil.OptVal(opts, []string{"--replay"})
+
+	switch {
+	case record != "" && replay != "":
+		return nil, &UsageError{Err: fmt.Errorf("--record and --replay cannot be used together")}
+
+	case replay != "":
+		f, err := har.Load(replay)
+		if err != nil {
+			return nil, err
+		}
+
+		rp := har.NewReplayer(f)
+		cfg.WrapTransport = func(http.RoundTripper) http.RoundTripper {
+			return rp
+		}
+
+	case record != "":
+		var rec *har.Recorder
+		cfg.WrapTransport = func(rt http.RoundTripper) http.RoundTripper {
+			rec = har.NewRecorder(rt, cfg.Redactor)
+			return rec
+		}
+
+		return func() error {
+			if rec == nil {
+				return nil
+			}
+			if err := rec.File().Save(record); err != nil {
+				return fmt.Errorf("unable to write HAR file: %w", err)
+			}
+			return nil
+		}, nil
+	}
+
+	return func() error { return nil }, nil
+}
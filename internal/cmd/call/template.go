@@ -0,0 +1,65 @@
+package call
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/lucasepe/resto/internal/env"
+	getoptutil "github.com/lucasepe/resto/internal/util/getopt"
+	"github.com/lucasepe/x/getopt"
+)
+
+// templateOptions holds the settings of the ${VAR} expansion applied
+// to the request URL, headers and body.
+type templateOptions struct {
+	enabled bool
+	strict  bool
+	vars    map[string]string
+}
+
+// templateOptionsFrom returns the template settings from the command line flags.
+//
+// Expansion is enabled by --template and implied by --var and --strict-vars,
+// so that bodies containing a literal '$' are sent untouched by default.
+func templateOptionsFrom(opts []getopt.OptArg) (templateOptions, error) {
+	vars, err := Vars(opts)
+	if err != nil {
+		return templateOptions{}, err
+	}
+
+	res := templateOptions{
+		strict: getoptutil.HasOpt(opts, []string{"--strict-vars"}),
+		vars:   vars,
+	}
+
+	res.enabled = res.strict || len(res.vars) > 0 ||
+		getoptutil.HasOpt(opts, []string{"--template"})
+
+	return res, nil
+}
+
+// Vars returns the template variables defined with --var key=value.
+func Vars(opts []getopt.OptArg) (map[string]string, error) {
+	res := map[string]string{}
+
+	for _, el := range getoptutil.AllOptArgs(opts, []string{"--var"}) {
+		key, val, ok := strings.Cut(el, "=")
+		key = strings.TrimSpace(key)
+		if !ok || key == "" {
+			return nil, fmt.Errorf("invalid variable format: %q (expected key=value)", el)
+		}
+		res[key] = val
+	}
+
+	return res, nil
+}
+
+// expand returns s with the variable references expanded,
+// or s as is when templating is not enabled.
+func (to templateOptions) expand(s string) (string, error) {
+	if !to.enabled {
+		return s, nil
+	}
+
+	return env.Expand(s, to.vars, to.strict)
+}
@@ -0,0 +1,64 @@
+package call
+
+import (
+	"testing"
+
+	"github.com/lucasepe/x/getopt"
+)
+
+func TestTemplateOptions(t *testing.T) {
+	tests := []struct {
+		name    string
+		opts    []getopt.OptArg
+		input   string
+		want    string
+		wantErr bool
+	}{
+		{
+			name:  "disabled by default",
+			opts:  nil,
+			input: `{"$ref": "${NAME}"}`,
+			want:  `{"$ref": "${NAME}"}`,
+		},
+		{
+			name: "enabled by --var",
+			opts: []getopt.OptArg{
+				{Option: "--var", Argument: "name=demo"},
+				{Option: "--var", Argument: "query=a=b"},
+			},
+			input: "/items/${name}?${query}",
+			want:  "/items/demo?a=b",
+		},
+		{
+			name: "strict mode",
+			opts: []getopt.OptArg{
+				{Option: "--strict-vars"},
+			},
+			input:   "/items/${RESTO_TEST_UNDEFINED}",
+			wantErr: true,
+		},
+		{
+			name: "invalid variable",
+			opts: []getopt.OptArg{
+				{Option: "--var", Argument: "novalue"},
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			to, err := templateOptionsFrom(tt.opts)
+			if err == nil {
+				var got string
+				got, err = to.expand(tt.input)
+				if err == nil && got != tt.want {
+					t.Errorf("got %q, want %q", got, tt.want)
+				}
+			}
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("unexpected error status: got err=%v, wantErr=%v", err, tt.wantErr)
+			}
+		})
+	}
+}
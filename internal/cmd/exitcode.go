@@ -0,0 +1,89 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/url"
+
+	"github.com/lucasepe/resto/internal/cmd/call"
+	"github.com/lucasepe/resto/internal/restclient"
+	"github.com/lucasepe/resto/internal/util/jq"
+	"github.com/lucasepe/resto/internal/util/retry"
+)
+
+const (
+	ExitOK              = 0
+	ExitFailure         = 1
+	ExitUsage           = 2
+	ExitConditionNotMet = 3
+	ExitHTTPClient      = 4
+	ExitHTTPServer      = 5
+	ExitNetwork         = 6
+	ExitTimeout         = 7
+	ExitJQ              = 8
+	ExitFailIf          = 9
+)
+
+// ExitCode maps the error returned by Run to the process exit code.
+//
+// When retries are exhausted the cause of the last attempt wins, so
+// a server that kept answering 503 exits with ExitHTTPServer and a
+// server that was never reachable exits with ExitNetwork; only when
+// the last attempt succeeded but the condition did not hold the exit
+// code is ExitConditionNotMet, as when a watched stream ends before
+// any event satisfied the condition.
+//
+// ExitTimeout is only for the --timeout deadline: a single attempt
+// that timed out (--request-timeout) is a network failure.
+func ExitCode(err error) int {
+	if err == nil {
+		return ExitOK
+	}
+
+	var (
+		usageErr   *call.UsageError
+		timeoutErr *call.TimeoutError
+		failErr    *retry.FailError
+		jqErr      *jq.Error
+		httpErr    *restclient.HTTPError
+		statusErr  *retry.StatusError
+		opErr      *net.OpError
+		dnsErr     *net.DNSError
+		urlErr     *url.Error
+	)
+
+	switch {
+	case errors.As(err, &timeoutErr):
+		return ExitTimeout
+	case errors.As(err, &usageErr):
+		return ExitUsage
+	case errors.As(err, &failErr):
+		return ExitFailIf
+	case errors.As(err, &jqErr):
+		return ExitJQ
+	case errors.As(err, &httpErr):
+		return exitCodeForStatus(httpErr.StatusCode)
+	case errors.As(err, &statusErr):
+		return exitCodeForStatus(statusErr.StatusCode)
+	case errors.As(err, &opErr), errors.As(err, &dnsErr), errors.Is(err, context.DeadlineExceeded):
+		return ExitNetwork
+	case errors.Is(err, retry.ErrExhausted), errors.Is(err, restclient.ErrStreamEnded):
+		return ExitConditionNotMet
+	case errors.As(err, &urlErr):
+		return ExitNetwork
+	default:
+		return ExitFailure
+	}
+}
+
+func exitCodeForStatus(code int) int {
+	switch {
+	case code >= 400 && code < 500:
+		return ExitHTTPClient
+	case code >= 500:
+		return ExitHTTPServer
+	default:
+		return ExitFailure
+	}
+}
@@ -0,0 +1,74 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/lucasepe/resto/internal/cmd/call"
+	"github.com/lucasepe/resto/internal/restclient"
+	"github.com/lucasepe/resto/internal/util/jq"
+	"github.com/lucasepe/resto/internal/util/retry"
+)
+
+func TestExitCode(t *testing.T) {
+	connRefused := &net.OpError{Op: "dial", Net: "tcp", Err: syscall.ECONNREFUSED}
+
+	urlError := func(err error) error {
+		return &url.Error{Op: "Get", URL: "http://example.com", Err: err}
+	}
+
+	tests := []struct {
+		name string
+		err  error
+		want int
+	}{
+		{"no error", nil, ExitOK},
+		{"generic error", errors.New("boom"), ExitFailure},
+		{"usage error", &call.UsageError{Err: errors.New("missing request uri")}, ExitUsage},
+		{"condition never met", urlError(retry.ErrExhausted), ExitConditionNotMet},
+		{"stream ended", restclient.ErrStreamEnded, ExitConditionNotMet},
+		{"http 404", &restclient.HTTPError{StatusCode: 404}, ExitHTTPClient},
+		{"http 503", &restclient.HTTPError{StatusCode: 503}, ExitHTTPServer},
+		{"retried 503 exhausted", urlError(fmt.Errorf("%w: %w", retry.ErrExhausted, &retry.StatusError{StatusCode: 503})), ExitHTTPServer},
+		{"connection refused", urlError(connRefused), ExitNetwork},
+		{"retried connection refused exhausted", urlError(fmt.Errorf("%w: %w", retry.ErrExhausted, connRefused)), ExitNetwork},
+		{"tls failure", urlError(errors.New("x509: certificate signed by unknown authority")), ExitNetwork},
+		{"deadline", &call.TimeoutError{Timeout: time.Second}, ExitTimeout},
+		{"client timeout", urlError(context.DeadlineExceeded), ExitNetwork},
+		{"retried request timeout exhausted", urlError(fmt.Errorf("%w: %w", retry.ErrExhausted, fmt.Errorf("request timeout of 1s exceeded: %w", context.DeadlineExceeded))), ExitNetwork},
+		{"fail condition met", urlError(&retry.FailError{Expr: `.status.phase == "Failed"`}), ExitFailIf},
+		{"jq error", urlError(&jq.Error{Expr: ".[", Err: errors.New("invalid JQ expression")}), ExitJQ},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := ExitCode(tc.err); got != tc.want {
+				t.Errorf("ExitCode(%v) = %d, want %d", tc.err, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestExitCode_UntilOnClientError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusNotFound)
+		fmt.Fprint(w, `{"message": "not found"}`)
+	}))
+	defer srv.Close()
+
+	err := call.Do(context.Background(), []string{
+		"--until", ".ready", "--max-attempts", "3", "--initial-delay", "1ms", srv.URL,
+	})
+	if got := ExitCode(err); got != ExitHTTPClient {
+		t.Errorf("ExitCode(%v) = %d, want %d", err, got, ExitHTTPClient)
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"os"
 
 	"github.com/lucasepe/resto/internal/cmd/call"
+	"github.com/lucasepe/resto/internal/cmd/run"
 	"github.com/lucasepe/resto/internal/env"
 	getoptutil "github.com/lucasepe/resto/internal/util/getopt"
 	ioutil "github.com/lucasepe/resto/internal/util/io"
@@ -23,6 +24,7 @@ type Action int
 const (
 	NoAction Action = iota
 	Call
+	RunWorkflow
 	ShowHelp
 	ShowVersion
 )
@@ -49,7 +51,11 @@ func Run(ctx context.Context) (err error) {
 		return nil
 	}
 
-	err = call.Do(os.Args[1:])
+	if act == RunWorkflow {
+		return run.Do(ctx, os.Args[2:])
+	}
+
+	err = call.Do(ctx, os.Args[1:])
 	if errors.Is(err, ioutil.ErrNoInputDetected) {
 		usage(os.Stderr)
 		return nil
@@ -59,6 +65,10 @@ func Run(ctx context.Context) (err error) {
 }
 
 func chosenAction(args []string) (Action, error) {
+	if len(args) > 0 && args[0] == "run" {
+		return RunWorkflow, nil
+	}
+
 	_, opts, err := getopt.GetOpt(args,
 		"",
 		[]string{"help", "version"},
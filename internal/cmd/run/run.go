@@ -0,0 +1,118 @@
+package run
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/lucasepe/resto/internal/cmd/call"
+	"github.com/lucasepe/resto/internal/restclient"
+	getoptutil "github.com/lucasepe/resto/internal/util/getopt"
+	"github.com/lucasepe/resto/internal/workflow"
+	"github.com/lucasepe/x/getopt"
+)
+
+// Do executes the workflow file given as first argument.
+//
+// It accepts the same client and retry flags of a single call,
+// plus --var and --strict-vars for the step templates.
+func Do(ctx context.Context, args []string) (err error) {
+	extras, opts, err := getopt.GetOpt(args,
+		"v",
+		append([]string{
+			"strict-vars",
+			"var=",
+		}, call.ClientFlags...),
+	)
+	if err != nil {
+		return &call.UsageError{Err: err}
+	}
+
+	if len(extras) < 1 {
+		return &call.UsageError{Err: fmt.Errorf("missing workflow file")}
+	}
+
+	wf, err := workflow.Load(extras[0])
+	if err != nil {
+		return err
+	}
+
+	vars, err := call.Vars(opts)
+	if err != nil {
+		return &call.UsageError{Err: err}
+	}
+
+	cfg, err := call.ClientConfig(opts)
+	if err != nil {
+		return err
+	}
+
+	saveHAR, err := call.HARTransport(opts, &cfg)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if serr := saveHAR(); serr != nil && err == nil {
+			err = serr
+		}
+	}()
+
+	retryOn, err := call.RetryOn(opts)
+	if err != nil {
+		return err
+	}
+
+	retryOpts := call.RetryOptions(opts)
+	if _, err := call.Strategy(retryOpts); err != nil {
+		return err
+	}
+
+	logger, err := call.Logger(opts, cfg.Verbose)
+	if err != nil {
+		return err
+	}
+	cfg.Logger = logger
+
+	cli, err := restclient.HTTPClientForConfig(cfg)
+	if err != nil {
+		return err
+	}
+
+	streams := restclient.IOStreams{
+		Out: os.Stdout,
+		Err: os.Stderr,
+	}
+	if cfg.Verbose {
+		streams.Out = io.Discard
+	}
+
+	runner := &workflow.Runner{
+		Client:       cli,
+		ServerURL:    cfg.ServerURL,
+		RetryOptions: retryOpts,
+		RetryOn:      retryOn,
+		Vars:         vars,
+		Strict:       getoptutil.HasOpt(opts, []string{"--strict-vars"}),
+		Streams:      streams,
+		Logger:       logger,
+	}
+
+	if retryOpts.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, retryOpts.Timeout)
+		defer cancel()
+	}
+
+	results, err := runner.Run(ctx, wf)
+	call.WriteFailBody(os.Stderr, err)
+	fmt.Fprintln(os.Stderr)
+	workflow.WriteSummary(os.Stderr, results)
+
+	if err != nil && errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return &call.TimeoutError{Timeout: retryOpts.Timeout}
+	}
+
+	return err
+}
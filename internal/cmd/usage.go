@@ -44,35 +44,149 @@ func usage(wri io.Writer) {
 	fmt.Fprintln(wri)
 
 	fmt.Fprint(wri, "USAGE:\n\n")
-	fmt.Fprintf(wri, "  %s [FLAGS] URL\n\n", appName)
+	fmt.Fprintf(wri, "  %s [FLAGS] URL\n", appName)
+	fmt.Fprintf(wri, "  %s run [FLAGS] WORKFLOW\n\n", appName)
+
+	fmt.Fprint(wri, "  The 'run' command executes the steps of a YAML workflow file in order.\n")
+	fmt.Fprint(wri, "  Each step declares method, url, headers, body, until, poll-url, fail-if and\n")
+	fmt.Fprint(wri, "  capture (JQ expressions whose results become ${VARS} for the next steps).\n")
+	fmt.Fprint(wri, "  It accepts the client and retry flags below, plus --var and --strict-vars.\n\n")
 
 	fmt.Fprint(wri, "FLAGS:\n\n")
 	fmt.Fprint(wri, "  -X, --request          Specify request method to use (default: GET).\n\n")
 	fmt.Fprint(wri, "  -H, --header           Add a custom request header (can be specified multiple times).\n")
 	fmt.Fprint(wri, "                         Format: 'Key: Value'.\n\n")
+	fmt.Fprint(wri, "  -f, --file             Read the request body from file (default: stdin).\n\n")
+	fmt.Fprint(wri, "      --yaml             Convert the YAML request body to JSON (implied for .yaml and\n")
+	fmt.Fprint(wri, "                         .yml files). Multi-document YAML sends one request per document.\n\n")
+	fmt.Fprint(wri, "      --template         Expand ${VAR} and $VAR references in the URL, headers and body.\n")
+	fmt.Fprint(wri, "                         Values come from --var flags, the environment and `.env`.\n\n")
+	fmt.Fprint(wri, "      --var              Define a template variable (can be specified multiple times).\n")
+	fmt.Fprint(wri, "                         Format: 'key=value'. Implies --template.\n\n")
+	fmt.Fprint(wri, "      --strict-vars      Fail on references to undefined variables. Implies --template.\n\n")
 	fmt.Fprint(wri, "      --proxy-url        HTTP proxy URL to use for the request.\n\n")
 	fmt.Fprint(wri, "  -u, --until            JQ expression to evaluate on JSON response.\n")
-	fmt.Fprint(wri, "                         Retries until it evaluates to true.\n\n")
+	fmt.Fprint(wri, "                         Retries until it evaluates to true. Requests other than GET\n")
+	fmt.Fprint(wri, "                         and HEAD are sent once, then the condition is polled with\n")
+	fmt.Fprint(wri, "                         GET requests (see --poll-url), unless --watch is set.\n")
+	fmt.Fprint(wri, "                         Error responses end the wait, except 429 and 503 carrying\n")
+	fmt.Fprint(wri, "                         Retry-After, which is honored.\n\n")
+	fmt.Fprint(wri, "      --poll-url         URL polled with GET until --until is true, after the request is\n")
+	fmt.Fprint(wri, "                         sent once (default: the Location header of the response or\n")
+	fmt.Fprint(wri, "                         the request URL). Relative URLs are resolved against the\n")
+	fmt.Fprint(wri, "                         request URL.\n\n")
+	fmt.Fprint(wri, "      --async            Follow the long-running operation started by the request: on\n")
+	fmt.Fprint(wri, "                         202 Accepted the Azure-AsyncOperation, Operation-Location or\n")
+	fmt.Fprint(wri, "                         Location URL is polled (honoring Retry-After) until the\n")
+	fmt.Fprint(wri, "                         status is Succeeded, Failed or Canceled (or done is true),\n")
+	fmt.Fprint(wri, "                         then the resulting resource is fetched and printed.\n\n")
+	fmt.Fprint(wri, "  -o, --output           Rendering of JSON responses: raw (default, byte-for-byte copy),\n")
+	fmt.Fprint(wri, "                         json, pretty-json or yaml. Key order is preserved.\n\n")
+	fmt.Fprint(wri, "      --fail-if          JQ expression evaluated on each JSON response together with\n")
+	fmt.Fprint(wri, "                         --until. Stops waiting as soon as it evaluates to true,\n")
+	fmt.Fprint(wri, "                         printing the offending response to stderr.\n\n")
+	fmt.Fprint(wri, "      --watch, --stream  Read the response as a stream of JSON events (NDJSON, json-seq,\n")
+	fmt.Fprint(wri, "                         Kubernetes watches), printing each one as soon as it arrives.\n")
+	fmt.Fprint(wri, "                         --until and --fail-if are evaluated on every event and the\n")
+	fmt.Fprint(wri, "                         stream is closed at the first match. Implied for NDJSON\n")
+	fmt.Fprint(wri, "                         and json-seq content types.\n")
+	fmt.Fprint(wri, "                         Server-sent events (text/event-stream) are printed as JSON\n")
+	fmt.Fprint(wri, "                         lines {id, event, data, retry}; --until and --fail-if see\n")
+	fmt.Fprint(wri, "                         the event data. Dropped connections are resumed with\n")
+	fmt.Fprint(wri, "                         Last-Event-ID, up to --max-attempts times, waiting the\n")
+	fmt.Fprint(wri, "                         server 'retry' (default: --initial-delay, max: --max-delay).\n\n")
+	fmt.Fprint(wri, "      --paginate         Follow the next page links and print every page. By default the\n")
+	fmt.Fprint(wri, "                         Link header with rel=\"next\" is followed (e.g., GitHub).\n\n")
+	fmt.Fprint(wri, "      --next             JQ expression extracting the next page URL (or cursor, see\n")
+	fmt.Fprint(wri, "                         --next-param) from each JSON page. Null, false or empty stops\n")
+	fmt.Fprint(wri, "                         the pagination. Implies --paginate.\n\n")
+	fmt.Fprint(wri, "      --next-param       Query parameter the cursor extracted by --next is sent in\n")
+	fmt.Fprint(wri, "                         (e.g., 'continue' for Kubernetes lists).\n\n")
+	fmt.Fprint(wri, "      --merge            Print a single JSON document instead of every page: arrays are\n")
+	fmt.Fprint(wri, "                         concatenated, as the array fields of objects (e.g., items).\n")
+	fmt.Fprint(wri, "                         Implies --paginate.\n\n")
+	fmt.Fprint(wri, "  -q, --query, --jq      JQ expression applied to the final JSON response.\n")
+	fmt.Fprint(wri, "                         Each result is printed on its own line as JSON.\n\n")
+	fmt.Fprint(wri, "  -r, --raw-output       With --query, print string results without quotes.\n\n")
+	fmt.Fprint(wri, "      --retry-on         Comma separated list of conditions that trigger a retry\n")
+	fmt.Fprint(wri, "                         instead of failing: status codes (429), status classes (5xx),\n")
+	fmt.Fprint(wri, "                         'connect-error' and 'timeout' (e.g., 5xx,429,connect-error).\n\n")
 	fmt.Fprint(wri, "      --max-attempts     The maximum number of retry attempts. The operation will be\n")
 	fmt.Fprint(wri, "                         retried up to this many times before giving up.\n\n")
 	fmt.Fprint(wri, "      --initial-delay    The starting delay duration before the first retry attempt.\n")
 	fmt.Fprint(wri, "                         Specify as a time duration (e.g., 100ms, 1s).\n")
 	fmt.Fprint(wri, "                         Determines how long to wait before retrying initially.\n\n")
 	fmt.Fprint(wri, "      --max-delay        The maximum delay duration allowed between retry attempts.\n")
-	fmt.Fprint(wri, "                         Subsequent retries will not exceed this delay.\n\n")
+	fmt.Fprint(wri, "                         Subsequent retries will not exceed this delay.\n")
+	fmt.Fprint(wri, "                         Also caps server provided Retry-After back-off.\n\n")
 	fmt.Fprint(wri, "      --max-jitter       The maximum random jitter added to the retry delay.\n")
 	fmt.Fprint(wri, "                         Specified as a time duration to spread out retry timing.\n\n")
-	fmt.Fprint(wri, "      --ca-cert          Base64-encoded CA certificate for verifying the server's TLS cert.\n\n")
-	fmt.Fprint(wri, "      --cert             Base64-encoded client certificate (PEM format) for TLS authentication.\n\n")
-	fmt.Fprint(wri, "      --cert-key         Base64-encoded private key (PEM format) for the client certificate.\n\n")
+	fmt.Fprint(wri, "      --backoff          How the delay grows between attempts: constant, linear,\n")
+	fmt.Fprint(wri, "                         exponential (default, plus --max-jitter), fibonacci,\n")
+	fmt.Fprint(wri, "                         decorrelated-jitter or full-jitter.\n\n")
+	fmt.Fprint(wri, "      --backoff-multiplier\n")
+	fmt.Fprint(wri, "                         Growth factor of the backoff: the exponential and full-jitter\n")
+	fmt.Fprint(wri, "                         curve (default: 2), the linear step in initial delays\n")
+	fmt.Fprint(wri, "                         (default: 1), the decorrelated-jitter spread (default: 3).\n")
+	fmt.Fprint(wri, "                         Must be at least 1, but for linear; constant and fibonacci\n")
+	fmt.Fprint(wri, "                         do not accept it.\n\n")
+	fmt.Fprint(wri, "      --timeout          The overall deadline for the whole call, retries included\n")
+	fmt.Fprint(wri, "                         (e.g., 5m).\n\n")
+	fmt.Fprint(wri, "      --request-timeout  The deadline for each single attempt (e.g., 10s).\n\n")
+	fmt.Fprint(wri, "      --record           Record every attempt (request, response, timings, attempt\n")
+	fmt.Fprint(wri, "                         number) to the given HAR 1.2 file, even when the call fails.\n\n")
+	fmt.Fprint(wri, "      --replay           Serve the responses recorded in the given HAR file instead of\n")
+	fmt.Fprint(wri, "                         calling the server; repeated requests get the recorded\n")
+	fmt.Fprint(wri, "                         responses in order, the last one is repeated.\n\n")
+	fmt.Fprint(wri, "      --ca-cert          CA certificate (PEM or base64-encoded PEM) for verifying the\n")
+	fmt.Fprint(wri, "                         server's TLS cert.\n\n")
+	fmt.Fprint(wri, "      --ca-file          Path to a PEM file with the CA certificates (instead of --ca-cert).\n\n")
+	fmt.Fprint(wri, "      --system-ca        Trust the system root CAs too, adding --ca-cert/--ca-file to them\n")
+	fmt.Fprint(wri, "                         instead of replacing them.\n\n")
+	fmt.Fprint(wri, "      --cert             Client certificate (PEM or base64-encoded PEM) for TLS authentication.\n\n")
+	fmt.Fprint(wri, "      --cert-file        Path to a PEM file with the client certificate (instead of --cert).\n\n")
+	fmt.Fprint(wri, "      --cert-key         Private key (PEM or base64-encoded PEM) for the client certificate.\n\n")
+	fmt.Fprint(wri, "      --key-file         Path to a PEM file with the private key (instead of --cert-key).\n\n")
 	fmt.Fprint(wri, "      --insecure         Skip TLS certificate verification (insecure, use with caution).\n\n")
 
+	fmt.Fprint(wri, "      --kubeconfig       Path to a kubeconfig file to load server URL, TLS material and\n")
+	fmt.Fprint(wri, "                         credentials from (default: $KUBECONFIG or ~/.kube/config).\n")
+	fmt.Fprint(wri, "                         A list of paths is merged as kubectl does. Users relying on\n")
+	fmt.Fprint(wri, "                         exec plugins or auth providers are not supported.\n\n")
+	fmt.Fprint(wri, "      --context          The kubeconfig context to use (default: current-context).\n\n")
+
 	fmt.Fprint(wri, "      --username         Username for Basic Auth. Used with --password.\n\n")
 	fmt.Fprint(wri, "      --password         Password for Basic Auth. Used with --username.\n\n")
 
 	fmt.Fprint(wri, "      --token            Bearer token for Authorization header.\n\n")
+	fmt.Fprint(wri, "      --token-file       Read the bearer token from a file, again whenever the file\n")
+	fmt.Fprint(wri, "                         changes (e.g. Kubernetes projected service account tokens).\n\n")
+	fmt.Fprint(wri, "      --token-command    Run a shell command and use its output as bearer token\n")
+	fmt.Fprint(wri, "                         (e.g. 'gcloud auth print-access-token').\n\n")
+	fmt.Fprint(wri, "      --token-command-ttl\n")
+	fmt.Fprint(wri, "                         How long the token command output is reused (default: 1m).\n\n")
+
+	fmt.Fprint(wri, "      --oauth-token-url  OAuth2 token endpoint: an access token is obtained with the\n")
+	fmt.Fprint(wri, "                         client credentials grant, cached until it expires and renewed\n")
+	fmt.Fprint(wri, "                         when needed (also once on a 401 response).\n\n")
+	fmt.Fprint(wri, "      --oauth-client-id  OAuth2 client id. Used with --oauth-token-url.\n\n")
+	fmt.Fprint(wri, "      --oauth-client-secret\n")
+	fmt.Fprint(wri, "                         OAuth2 client secret. Used with --oauth-client-id.\n\n")
+	fmt.Fprint(wri, "      --oauth-scopes     Comma separated scopes to request (optional).\n\n")
+	fmt.Fprint(wri, "      --oauth-audience   Audience of the requested token (optional).\n\n")
 
 	fmt.Fprint(wri, "  -v, --verbose          Enable verbose output (prints headers, body, debug info).\n\n")
+	fmt.Fprint(wri, "      --redact-header    Header to hide from verbose output, logs and recordings (can be\n")
+	fmt.Fprint(wri, "                         specified multiple times). Authorization, Cookie, Set-Cookie\n")
+	fmt.Fprint(wri, "                         and Proxy-Authorization are always redacted.\n\n")
+	fmt.Fprint(wri, "      --redact-field     JSON body field to hide, at any depth (can be specified multiple\n")
+	fmt.Fprint(wri, "                         times). password, token, access_token, refresh_token, id_token\n")
+	fmt.Fprint(wri, "                         and client_secret are always redacted.\n\n")
+	fmt.Fprint(wri, "      --show-secrets     Do not redact anything (use with caution).\n\n")
+	fmt.Fprint(wri, "      --log-format       Write structured logs to stderr: text or json. One record per\n")
+	fmt.Fprint(wri, "                         attempt (attempt, delay_ms, method, url, status, latency_ms,\n")
+	fmt.Fprint(wri, "                         bytes, until, error). With --verbose, requests and responses\n")
+	fmt.Fprint(wri, "                         are logged as debug records instead of the curl-like dumps.\n\n")
 	fmt.Fprint(wri, "      --version          Show version and exit.\n")
 	fmt.Fprint(wri, "      --help             Show help and exit.\n")
 	fmt.Fprint(wri, "\n\n")
@@ -80,24 +194,45 @@ func usage(wri io.Writer) {
 	fmt.Fprint(wri, "ENVIRONMENT:\n\n")
 	fmt.Fprint(wri, "  Many long-form flags can alternatively be set using environment variables.\n\n")
 	fmt.Fprint(wri, "  You can define them in a `.env` file or export them in your shell.\n\n")
-	fmt.Fprint(wri, "  +---------------------+-----------------------+\n")
-	fmt.Fprint(wri, "  |  flag               |  environment variable |\n")
-	fmt.Fprint(wri, "  |---------------------+-----------------------|\n")
-	fmt.Fprint(wri, "  |                     |  SERVER_URL           |\n")
-	fmt.Fprint(wri, "  |     --proxy-url     |  PROXY_URL            |\n")
-	fmt.Fprint(wri, "  |     --max-attempts  |  MAX_ATTEMPTS         |\n")
-	fmt.Fprint(wri, "  |     --initial-delay |  INITIAL_DELAY        |\n")
-	fmt.Fprint(wri, "  |     --max-delay     |  MAX_DELAY            |\n")
-	fmt.Fprint(wri, "  |     --max-jitter    |  MAX_JITTER           |\n")
-	fmt.Fprint(wri, "  |     --ca-cert       |  CA_CERT              |\n")
-	fmt.Fprint(wri, "  |     --cert          |  CERT                 |\n")
-	fmt.Fprint(wri, "  |     --cert-key      |  CERT_KEY             |\n")
-	fmt.Fprint(wri, "  |     --insecure      |  INSECURE             |\n")
-	fmt.Fprint(wri, "  |     --token         |  TOKEN                |\n")
-	fmt.Fprint(wri, "  |     --username      |  USERNAME             |\n")
-	fmt.Fprint(wri, "  |     --password      |  PASSWORD             |\n")
-	fmt.Fprint(wri, "  | -v, --verbose       |  VERBOSE              |\n")
-	fmt.Fprint(wri, "  +---------------------+-----------------------+\n\n")
+	fmt.Fprint(wri, "  +---------------------------+-----------------------+\n")
+	fmt.Fprint(wri, "  |  flag                     |  environment variable |\n")
+	fmt.Fprint(wri, "  |---------------------------+-----------------------|\n")
+	fmt.Fprint(wri, "  |                           |  SERVER_URL           |\n")
+	fmt.Fprint(wri, "  |     --proxy-url           |  PROXY_URL            |\n")
+	fmt.Fprint(wri, "  | -u, --until               |  UNTIL                |\n")
+	fmt.Fprint(wri, "  |     --fail-if             |  FAIL_IF              |\n")
+	fmt.Fprint(wri, "  | -o, --output              |  OUTPUT               |\n")
+	fmt.Fprint(wri, "  |     --retry-on            |  RETRY_ON             |\n")
+	fmt.Fprint(wri, "  |     --max-attempts        |  MAX_ATTEMPTS         |\n")
+	fmt.Fprint(wri, "  |     --initial-delay       |  INITIAL_DELAY        |\n")
+	fmt.Fprint(wri, "  |     --max-delay           |  MAX_DELAY            |\n")
+	fmt.Fprint(wri, "  |     --max-jitter          |  MAX_JITTER           |\n")
+	fmt.Fprint(wri, "  |     --backoff             |  BACKOFF              |\n")
+	fmt.Fprint(wri, "  |     --backoff-multiplier  |  BACKOFF_MULTIPLIER   |\n")
+	fmt.Fprint(wri, "  |     --timeout             |  TIMEOUT              |\n")
+	fmt.Fprint(wri, "  |     --request-timeout     |  REQUEST_TIMEOUT      |\n")
+	fmt.Fprint(wri, "  |     --ca-cert             |  CA_CERT              |\n")
+	fmt.Fprint(wri, "  |     --ca-file             |  CA_FILE              |\n")
+	fmt.Fprint(wri, "  |     --system-ca           |  SYSTEM_CA            |\n")
+	fmt.Fprint(wri, "  |     --cert                |  CERT                 |\n")
+	fmt.Fprint(wri, "  |     --cert-file           |  CERT_FILE            |\n")
+	fmt.Fprint(wri, "  |     --cert-key            |  CERT_KEY             |\n")
+	fmt.Fprint(wri, "  |     --key-file            |  KEY_FILE             |\n")
+	fmt.Fprint(wri, "  |     --insecure            |  INSECURE             |\n")
+	fmt.Fprint(wri, "  |     --token               |  TOKEN                |\n")
+	fmt.Fprint(wri, "  |     --token-file          |  TOKEN_FILE           |\n")
+	fmt.Fprint(wri, "  |     --token-command       |  TOKEN_COMMAND        |\n")
+	fmt.Fprint(wri, "  |     --token-command-ttl   |  TOKEN_COMMAND_TTL    |\n")
+	fmt.Fprint(wri, "  |     --oauth-token-url     |  OAUTH_TOKEN_URL      |\n")
+	fmt.Fprint(wri, "  |     --oauth-client-id     |  OAUTH_CLIENT_ID      |\n")
+	fmt.Fprint(wri, "  |     --oauth-client-secret |  OAUTH_CLIENT_SECRET  |\n")
+	fmt.Fprint(wri, "  |     --oauth-scopes        |  OAUTH_SCOPES         |\n")
+	fmt.Fprint(wri, "  |     --oauth-audience      |  OAUTH_AUDIENCE       |\n")
+	fmt.Fprint(wri, "  |     --username            |  USERNAME             |\n")
+	fmt.Fprint(wri, "  |     --password            |  PASSWORD             |\n")
+	fmt.Fprint(wri, "  | -v, --verbose             |  VERBOSE              |\n")
+	fmt.Fprint(wri, "  |     --log-format          |  LOG_FORMAT           |\n")
+	fmt.Fprint(wri, "  +---------------------------+-----------------------+\n\n")
 
 	fmt.Fprint(wri, "  Example `.env` file:\n")
 	fmt.Fprint(wri, "    TOKEN=your-token-here\n")
@@ -110,6 +245,21 @@ func usage(wri io.Writer) {
 	fmt.Fprint(wri, "                  sensitive data to version control.\n")
 	fmt.Fprint(wri, "\n\n")
 
+	fmt.Fprint(wri, "EXIT CODES:\n\n")
+	fmt.Fprint(wri, "  0  Success.\n")
+	fmt.Fprint(wri, "  1  Generic failure.\n")
+	fmt.Fprint(wri, "  2  Usage error (unknown flag, missing or invalid argument).\n")
+	fmt.Fprint(wri, "  3  Retries exhausted (or the stream ended), the --until condition was never met.\n")
+	fmt.Fprint(wri, "  4  HTTP client error (4xx).\n")
+	fmt.Fprint(wri, "  5  HTTP server error (5xx).\n")
+	fmt.Fprint(wri, "  6  Network error (connection refused, DNS, TLS, --request-timeout).\n")
+	fmt.Fprint(wri, "  7  Timeout, the --timeout deadline was hit.\n")
+	fmt.Fprint(wri, "  8  JQ error (invalid expression or invalid JSON response).\n")
+	fmt.Fprint(wri, "  9  The --fail-if condition was met.\n")
+	fmt.Fprint(wri, "\n  When retries are exhausted the cause of the last attempt decides the\n")
+	fmt.Fprint(wri, "  exit code: a server that kept answering 503 exits with 5.\n")
+	fmt.Fprint(wri, "\n\n")
+
 	fmt.Fprint(wri, "EXAMPLES:\n\n")
 
 	fmt.Fprint(wri, " » Perform a simple GET request:\n\n")
@@ -121,12 +271,71 @@ func usage(wri io.Writer) {
 	fmt.Fprint(wri, " » POST a JSON body from stdin:\n\n")
 	fmt.Fprintf(wri, "     echo '{\"hello\": \"world\"}' | %s -X POST -H \"Content-Type: application/json\" https://httpbin.org/post\n\n", appName)
 
+	fmt.Fprint(wri, " » Create a Kubernetes resource authored as YAML:\n\n")
+	fmt.Fprintf(wri, "     %s -X POST -f testdata/delayed-pod.yaml /api/v1/namespaces/default/pods\n\n", appName)
+
+	fmt.Fprint(wri, " » POST a templated body, failing on undefined variables:\n\n")
+	fmt.Fprintf(wri, "     %s -X POST --strict-vars --var name=demo -f pod.json '${SERVER_URL}/api/v1/namespaces/${NS}/pods'\n\n", appName)
+
 	fmt.Fprint(wri, " » Retry until a JQ expression is true:\n\n")
 	fmt.Fprintf(wri, "     %s --until '.status == \"ok\"' https://example.com/api/status\n\n", appName)
 
+	fmt.Fprint(wri, " » Start a job (202 Accepted + Location) and wait until it completes:\n\n")
+	fmt.Fprintf(wri, "     %s -X POST -f job.json --until '.status == \"done\"' https://example.com/api/jobs\n\n", appName)
+
+	fmt.Fprint(wri, " » Create an Azure resource and print it once provisioned:\n\n")
+	fmt.Fprintf(wri, "     %s -X PUT --async -f vnet.json \"$ARM_URL/virtualNetworks/demo?api-version=2024-05-01\"\n\n", appName)
+
+	fmt.Fprint(wri, " » Show a Kubernetes object as YAML:\n\n")
+	fmt.Fprintf(wri, "     %s -o yaml /api/v1/namespaces/default/pods/demo\n\n", appName)
+
+	fmt.Fprint(wri, " » Stop waiting as soon as the pod fails:\n\n")
+	fmt.Fprintf(wri, "     %s --until '.status.phase == \"Running\"' --fail-if '.status.phase == \"Failed\"' $POD_URL\n\n", appName)
+
+	fmt.Fprint(wri, " » Watch the pods of a namespace until one of them fails:\n\n")
+	fmt.Fprintf(wri, "     %s --watch --until '.object.status.phase == \"Failed\"' '/api/v1/namespaces/default/pods?watch=true'\n\n", appName)
+
+	fmt.Fprint(wri, " » Follow the progress events of a job until it completes:\n\n")
+	fmt.Fprintf(wri, "     %s --until '.state == \"completed\"' https://example.com/jobs/42/events\n\n", appName)
+
+	fmt.Fprint(wri, " » Extract fields from the JSON response:\n\n")
+	fmt.Fprintf(wri, "     %s -r --query '.items[].metadata.name' /api/v1/namespaces/default/pods\n\n", appName)
+
+	fmt.Fprint(wri, " » List all the pods, 100 at a time, as a single document:\n\n")
+	fmt.Fprintf(wri, "     %s --merge --next .metadata.continue --next-param continue '/api/v1/pods?limit=100'\n\n", appName)
+
+	fmt.Fprint(wri, " » Fetch every page of a GitHub listing:\n\n")
+	fmt.Fprintf(wri, "     %s --paginate -q '.[].full_name' 'https://api.github.com/orgs/golang/repos?per_page=100'\n\n", appName)
+
+	fmt.Fprint(wri, " » Wait for a service to come up, retrying on server and connection errors:\n\n")
+	fmt.Fprintf(wri, "     %s --retry-on 5xx,429,connect-error,timeout http://localhost:8080/healthz\n\n", appName)
+
+	fmt.Fprint(wri, " » Record a failing wait in CI and reproduce it offline:\n\n")
+	fmt.Fprintf(wri, "     %s --record wait.har --until '.status.phase == \"Running\"' $POD_URL\n", appName)
+	fmt.Fprintf(wri, "     %s --replay wait.har --until '.status.phase == \"Running\"' $POD_URL\n\n", appName)
+
 	fmt.Fprint(wri, " » Use Basic Auth credentials:\n\n")
 	fmt.Fprintf(wri, "     %s --username user --password pass https://httpbin.org/basic-auth/user/pass\n\n", appName)
 
+	fmt.Fprint(wri, " » Wait for a Kubernetes pod to become ready using your kubeconfig:\n\n")
+	fmt.Fprintf(wri, "     %s --context kind-dev --until '.status.phase == \"Running\"' /api/v1/namespaces/default/pods/demo\n\n", appName)
+
+	fmt.Fprint(wri, " » Create a pod, wait until it runs, then delete it:\n\n")
+	fmt.Fprint(wri, "     steps:\n")
+	fmt.Fprint(wri, "       - name: create\n")
+	fmt.Fprint(wri, "         method: POST\n")
+	fmt.Fprint(wri, "         url: /api/v1/namespaces/default/pods\n")
+	fmt.Fprint(wri, "         headers: { Content-Type: application/json }\n")
+	fmt.Fprint(wri, "         body: '{\"kind\": \"Pod\", ...}'\n")
+	fmt.Fprint(wri, "         capture: { POD: .metadata.name }\n")
+	fmt.Fprint(wri, "       - name: wait\n")
+	fmt.Fprint(wri, "         url: /api/v1/namespaces/default/pods/${POD}\n")
+	fmt.Fprint(wri, "         until: .status.phase == \"Running\"\n")
+	fmt.Fprint(wri, "       - name: delete\n")
+	fmt.Fprint(wri, "         method: DELETE\n")
+	fmt.Fprint(wri, "         url: /api/v1/namespaces/default/pods/${POD}\n\n")
+	fmt.Fprintf(wri, "     %s run --context kind-dev workflow.yaml\n\n", appName)
+
 	fmt.Fprint(wri, " » Send request via HTTP proxy:\n\n")
 	fmt.Fprintf(wri, "     %s --proxy-url http://localhost:8080 https://httpbin.org/ip\n\n", appName)
 
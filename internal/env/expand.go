@@ -0,0 +1,37 @@
+package env
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// Expand replaces ${NAME} and $NAME references in s with the values
+// found in vars or, as fallback, in the process environment (which
+// also holds the variables loaded from the `.env` file).
+//
+// Unlike the `.env` parser, variable names may contain lowercase letters.
+// A reference can be escaped with a backslash (e.g. \$NAME) to keep it literal.
+//
+// If strict is true, references to undefined variables are reported as an
+// error; otherwise they are replaced by an empty string.
+func Expand(s string, vars map[string]string, strict bool) (string, error) {
+	var missing []string
+
+	res := expand(templateVarRegex, s, vars, func(name string) {
+		if !slices.Contains(missing, name) {
+			missing = append(missing, name)
+		}
+	}, true)
+
+	if strict && len(missing) > 0 {
+		return "", fmt.Errorf("undefined variables: %s", strings.Join(missing, ", "))
+	}
+
+	return res, nil
+}
+
+var (
+	templateVarRegex = regexp.MustCompile(`(\\)?(\$)(\()?(?:\{([A-Za-z_][A-Za-z0-9_]*)\}|([A-Za-z_][A-Za-z0-9_]*))`)
+)
@@ -0,0 +1,66 @@
+package env
+
+import (
+	"testing"
+)
+
+func TestExpand(t *testing.T) {
+	t.Setenv("RESTO_TEST_HOST", "example.com")
+
+	vars := map[string]string{
+		"name": "demo",
+		"ID":   "42",
+	}
+
+	tests := []struct {
+		name    string
+		input   string
+		strict  bool
+		want    string
+		wantErr bool
+	}{
+		{
+			name:  "braces and plain references",
+			input: `{"name": "${name}", "id": $ID}`,
+			want:  `{"name": "demo", "id": 42}`,
+		},
+		{
+			name:  "environment fallback",
+			input: "https://${RESTO_TEST_HOST}/items/${ID}",
+			want:  "https://example.com/items/42",
+		},
+		{
+			name:  "escaped reference",
+			input: `price: \$ID`,
+			want:  `price: $ID`,
+		},
+		{
+			name:  "command substitution is left alone",
+			input: `{"at": "$(date)", "id": "$(echo ${ID})"}`,
+			want:  `{"at": "$(date)", "id": "$(echo 42)"}`,
+		},
+		{
+			name:  "undefined is empty when not strict",
+			input: "a${RESTO_TEST_UNDEFINED}b",
+			want:  "ab",
+		},
+		{
+			name:    "undefined fails when strict",
+			input:   "${RESTO_TEST_UNDEFINED} ${name}",
+			strict:  true,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Expand(tt.input, vars, tt.strict)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("unexpected error status: got err=%v, wantErr=%v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
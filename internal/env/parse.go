@@ -256,22 +256,43 @@ var (
 )
 
 func expandVariables(v string, m map[string]string) string {
-	return expandVarRegex.ReplaceAllStringFunc(v, func(s string) string {
-		submatch := expandVarRegex.FindStringSubmatch(s)
+	return expand(expandVarRegex, v, m, nil, false)
+}
+
+// expand replaces the variable references matched by re with the values
+// found in m or, as fallback, in the process environment. Unresolved
+// names are reported to onMissing, if not nil, and replaced by an empty string.
+// If commands is true, command substitutions such as $(NAME) are left as is.
+func expand(re *regexp.Regexp, v string, m map[string]string, onMissing func(string), commands bool) string {
+	return re.ReplaceAllStringFunc(v, func(s string) string {
+		submatch := re.FindStringSubmatch(s)
 
 		if submatch == nil {
 			return s
 		}
-		if submatch[1] == "\\" || submatch[2] == "(" {
+		// the name is either the 4th group or, for regexps that
+		// tell ${NAME} apart from $NAME, the 5th one
+		name := submatch[4]
+		if name == "" && len(submatch) > 5 {
+			name = submatch[5]
+		}
+
+		if submatch[1] == "\\" {
 			return submatch[0][1:]
-		} else if submatch[4] != "" {
-			if val, ok := m[submatch[4]]; ok {
+		} else if commands && submatch[3] == "(" {
+			// command substitution, not a variable
+			return s
+		} else if name != "" {
+			if val, ok := m[name]; ok {
 				return val
 			}
-			if val, ok := os.LookupEnv(submatch[4]); ok {
+			if val, ok := os.LookupEnv(name); ok {
 				return val
 			}
-			return m[submatch[4]]
+			if onMissing != nil {
+				onMissing(name)
+			}
+			return m[name]
 		}
 		return s
 	})
@@ -0,0 +1,43 @@
+package env
+
+import (
+	"testing"
+)
+
+func TestParseBytes(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  map[string]string
+	}{
+		{
+			name:  "plain and quoted values",
+			input: "FOO=bar\nBAZ=\"qux quux\"\n",
+			want:  map[string]string{"FOO": "bar", "BAZ": "qux quux"},
+		},
+		{
+			name:  "variable references",
+			input: "HOST=example.com\nURL=https://${HOST}/$HOST\n",
+			want:  map[string]string{"HOST": "example.com", "URL": "https://example.com/example.com"},
+		},
+		{
+			name:  "command substitution syntax expands the name",
+			input: "BAR=x\nFOO=$(BAR)\n",
+			want:  map[string]string{"BAR": "x", "FOO": "x)"},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got := map[string]string{}
+			if err := parseBytes([]byte(tc.input), got); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			for k, want := range tc.want {
+				if got[k] != want {
+					t.Errorf("%s: got %q, want %q", k, got[k], want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,156 @@
+// Package har records HTTP traffic in HAR 1.2 format and replays it.
+//
+// See http://www.softwareishard.com/blog/har-12-spec/
+package har
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+	"unicode/utf8"
+)
+
+// File is the root of a HAR document.
+type File struct {
+	Log Log `json:"log"`
+}
+
+type Log struct {
+	Version string  `json:"version"`
+	Creator Creator `json:"creator"`
+	Entries []Entry `json:"entries"`
+}
+
+type Creator struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+// Entry is a single HTTP exchange: one attempt of a request.
+type Entry struct {
+	StartedDateTime time.Time `json:"startedDateTime"`
+	// Time is the total elapsed time of the exchange, in milliseconds.
+	Time     float64  `json:"time"`
+	Request  Request  `json:"request"`
+	Response Response `json:"response"`
+	Cache    struct{} `json:"cache"`
+	Timings  Timings  `json:"timings"`
+	// Attempt is the attempt number of the request (custom field).
+	Attempt int `json:"_attempt,omitempty"`
+	// Error is the transport error of the attempt, if any (custom field).
+	Error string `json:"_error,omitempty"`
+}
+
+type Request struct {
+	Method      string      `json:"method"`
+	URL         string      `json:"url"`
+	HTTPVersion string      `json:"httpVersion"`
+	Cookies     []NameValue `json:"cookies"`
+	Headers     []NameValue `json:"headers"`
+	QueryString []NameValue `json:"queryString"`
+	PostData    *PostData   `json:"postData,omitempty"`
+	HeadersSize int         `json:"headersSize"`
+	BodySize    int         `json:"bodySize"`
+}
+
+type Response struct {
+	Status      int         `json:"status"`
+	StatusText  string      `json:"statusText"`
+	HTTPVersion string      `json:"httpVersion"`
+	Cookies     []NameValue `json:"cookies"`
+	Headers     []NameValue `json:"headers"`
+	Content     Content     `json:"content"`
+	RedirectURL string      `json:"redirectURL"`
+	HeadersSize int         `json:"headersSize"`
+	BodySize    int         `json:"bodySize"`
+}
+
+type NameValue struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+type PostData struct {
+	MimeType string `json:"mimeType"`
+	Text     string `json:"text"`
+}
+
+type Content struct {
+	Size     int    `json:"size"`
+	MimeType string `json:"mimeType"`
+	Text     string `json:"text,omitempty"`
+	// Encoding is "base64" when Text is not valid UTF-8.
+	Encoding string `json:"encoding,omitempty"`
+}
+
+// Timings are in milliseconds; -1 means not applicable.
+type Timings struct {
+	Blocked float64 `json:"blocked"`
+	DNS     float64 `json:"dns"`
+	Connect float64 `json:"connect"`
+	Send    float64 `json:"send"`
+	Wait    float64 `json:"wait"`
+	Receive float64 `json:"receive"`
+	SSL     float64 `json:"ssl"`
+}
+
+// Load reads a HAR document from file.
+func Load(filename string) (*File, error) {
+	src, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	var res File
+	if err := json.Unmarshal(src, &res); err != nil {
+		return nil, fmt.Errorf("unable to parse HAR file %q: %w", filename, err)
+	}
+
+	return &res, nil
+}
+
+// Save writes the HAR document to file, indented.
+func (f *File) Save(filename string) error {
+	bin, err := json.MarshalIndent(f, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(filename, append(bin, '\n'), 0o600)
+}
+
+// Body returns the decoded response body.
+func (c *Content) Body() ([]byte, error) {
+	if c.Encoding == "base64" {
+		return base64.StdEncoding.DecodeString(c.Text)
+	}
+	return []byte(c.Text), nil
+}
+
+func contentOf(body []byte, mimeType string) Content {
+	res := Content{Size: len(body), MimeType: mimeType}
+	if utf8.Valid(body) {
+		res.Text = string(body)
+	} else {
+		res.Text = base64.StdEncoding.EncodeToString(body)
+		res.Encoding = "base64"
+	}
+	return res
+}
+
+func nameValues(h http.Header) []NameValue {
+	res := []NameValue{}
+	for _, name := range sortedKeys(h) {
+		for _, val := range h[name] {
+			res = append(res, NameValue{Name: name, Value: val})
+		}
+	}
+	return res
+}
+
+func millis(d time.Duration) float64 {
+	return float64(d.Microseconds()) / 1000
+}
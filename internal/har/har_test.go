@@ -0,0 +1,159 @@
+package har
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lucasepe/resto/internal/util/redact"
+	"github.com/lucasepe/resto/internal/util/retry"
+	"github.com/stretchr/testify/require"
+)
+
+func newRetryClient(next http.RoundTripper) *http.Client {
+	retryOn, _ := retry.ParseRetryOn("5xx")
+
+	return &http.Client{
+		Transport: retry.NewRoundTripper(next, retry.RoundTripperOptions{
+			Until:    `.phase == "Running"`,
+			RetryOn:  retryOn,
+			Strategy: retry.Exp(),
+			Retrier: retry.NewRetrier(retry.RetryOptions{
+				InitialDelay: time.Millisecond,
+				MaxDelay:     5 * time.Millisecond,
+				MaxAttempts:  10,
+			}),
+		}),
+	}
+}
+
+func TestRecordAndReplay(t *testing.T) {
+	var calls int
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if calls == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+
+		phase := "Pending"
+		if calls > 2 {
+			phase = "Running"
+		}
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"phase": %q}`, phase)
+	}))
+	defer ts.Close()
+
+	rec := NewRecorder(http.DefaultTransport, nil)
+
+	req, _ := http.NewRequestWithContext(context.Background(), http.MethodPost, ts.URL+"/pods?ns=default", strings.NewReader(`{"kind": "Pod"}`))
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := newRetryClient(rec).Do(req)
+	require.NoError(t, err)
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	require.JSONEq(t, `{"phase": "Running"}`, string(body))
+
+	filename := filepath.Join(t.TempDir(), "pods.har")
+	require.NoError(t, rec.File().Save(filename))
+
+	f, err := Load(filename)
+	require.NoError(t, err)
+	require.Equal(t, "1.2", f.Log.Version)
+	require.Len(t, f.Log.Entries, 3)
+
+	for i, el := range f.Log.Entries {
+		require.Equal(t, i+1, el.Attempt)
+		require.Equal(t, http.MethodPost, el.Request.Method)
+		require.Equal(t, []NameValue{{Name: "ns", Value: "default"}}, el.Request.QueryString)
+		require.GreaterOrEqual(t, el.Timings.Wait, float64(0))
+	}
+	require.Equal(t, http.StatusServiceUnavailable, f.Log.Entries[0].Response.Status)
+	require.Equal(t, `{"kind": "Pod"}`, f.Log.Entries[0].Request.PostData.Text)
+	require.Equal(t, `{"phase": "Pending"}`, f.Log.Entries[1].Response.Content.Text)
+
+	t.Run("replay", func(t *testing.T) {
+		rp := NewReplayer(f)
+
+		req, _ := http.NewRequestWithContext(context.Background(), http.MethodPost, ts.URL+"/pods?ns=default", strings.NewReader(`{}`))
+
+		resp, err := newRetryClient(rp).Do(req)
+		require.NoError(t, err)
+		body, _ := io.ReadAll(resp.Body)
+		require.JSONEq(t, `{"phase": "Running"}`, string(body))
+		require.Equal(t, 3, calls, "replay must not reach the server")
+	})
+
+	t.Run("replay of an unknown request", func(t *testing.T) {
+		req, _ := http.NewRequest(http.MethodGet, ts.URL+"/nodes", nil)
+
+		_, err := NewReplayer(f).RoundTrip(req)
+		require.ErrorContains(t, err, "no recorded response for GET "+ts.URL+"/nodes")
+	})
+}
+
+func TestRecordTransportError(t *testing.T) {
+	ts := httptest.NewServer(http.NotFoundHandler())
+	url := ts.URL
+	ts.Close()
+
+	rec := NewRecorder(http.DefaultTransport, nil)
+	req, _ := http.NewRequest(http.MethodGet, url, nil)
+	_, err := rec.RoundTrip(req)
+	require.Error(t, err)
+
+	f := rec.File()
+	require.Len(t, f.Log.Entries, 1)
+	require.Equal(t, 0, f.Log.Entries[0].Response.Status)
+	require.Contains(t, f.Log.Entries[0].Error, "connection refused")
+
+	// the replayed error is still a connect error for --retry-on
+	retryOn, _ := retry.ParseRetryOn("connect-error")
+	_, err = NewReplayer(f).RoundTrip(req)
+	require.True(t, retryOn.RetryError(err))
+}
+
+func TestContentOf(t *testing.T) {
+	bin := []byte{0xff, 0x00, 0x10}
+
+	c := contentOf(bin, "application/octet-stream")
+	require.Equal(t, "base64", c.Encoding)
+
+	got, err := c.Body()
+	require.NoError(t, err)
+	require.Equal(t, bin, got)
+}
+
+func TestRecordRedacted(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Set-Cookie", "session=abc")
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"access_token": "xyz", "expires_in": 3600}`)
+	}))
+	defer ts.Close()
+
+	rec := NewRecorder(http.DefaultTransport, redact.New(nil, nil))
+
+	req, _ := http.NewRequest(http.MethodPost, ts.URL, strings.NewReader(`{"password": "s3cr3t"}`))
+	req.Header.Set("Authorization", "Bearer abc")
+
+	resp, err := rec.RoundTrip(req)
+	require.NoError(t, err)
+	io.ReadAll(resp.Body)
+	resp.Body.Close()
+
+	entries := rec.File().Log.Entries
+	require.Len(t, entries, 1)
+	require.Contains(t, entries[0].Request.Headers, NameValue{Name: "Authorization", Value: redact.Placeholder})
+	require.Equal(t, `{"password":"REDACTED"}`, entries[0].Request.PostData.Text)
+	require.Contains(t, entries[0].Response.Headers, NameValue{Name: "Set-Cookie", Value: redact.Placeholder})
+	require.Equal(t, `{"access_token":"REDACTED","expires_in":3600}`, entries[0].Response.Content.Text)
+}
@@ -0,0 +1,267 @@
+package har
+
+import (
+	"bytes"
+	"crypto/tls"
+	"io"
+	"net/http"
+	"net/http/httptrace"
+	"runtime/debug"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/lucasepe/resto/internal/util/redact"
+	"github.com/lucasepe/resto/internal/util/retry"
+)
+
+// Recorder is an http.RoundTripper that records every exchange made
+// through it; placed under the retry round tripper it records every
+// single attempt.
+//
+// Responses are recorded when their body has been read or closed,
+// so that streaming responses are captured as consumed.
+type Recorder struct {
+	next     http.RoundTripper
+	redactor *redact.Redactor
+
+	mu      sync.Mutex
+	entries []Entry
+}
+
+// NewRecorder returns a Recorder that sends the requests to next.
+// The secrets are hidden from the recording by the redactor, if any.
+func NewRecorder(next http.RoundTripper, redactor *redact.Redactor) *Recorder {
+	return &Recorder{next: next, redactor: redactor}
+}
+
+func (r *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	var reqBody []byte
+	if req.Body != nil && req.Body != http.NoBody {
+		var err error
+		if reqBody, err = io.ReadAll(req.Body); err != nil {
+			return nil, err
+		}
+		req.Body.Close()
+		req.Body = io.NopCloser(bytes.NewReader(reqBody))
+	}
+
+	tt := &traceTimes{start: time.Now()}
+	resp, err := r.next.RoundTrip(req.WithContext(
+		httptrace.WithClientTrace(req.Context(), tt.clientTrace())))
+
+	entry := Entry{
+		StartedDateTime: tt.start,
+		Request:         r.requestOf(req, reqBody),
+		Attempt:         retry.AttemptFrom(req.Context()),
+	}
+
+	if err != nil {
+		entry.Error = err.Error()
+		entry.Response = Response{Cookies: []NameValue{}, Headers: []NameValue{}, HeadersSize: -1, BodySize: -1}
+		entry.Timings, entry.Time = tt.timings(time.Now())
+		r.add(entry)
+		return nil, err
+	}
+
+	tt.headers(time.Now())
+
+	entry.Response = Response{
+		Status:      resp.StatusCode,
+		StatusText:  http.StatusText(resp.StatusCode),
+		HTTPVersion: resp.Proto,
+		Cookies:     []NameValue{},
+		Headers:     nameValues(r.redactor.Header(resp.Header)),
+		HeadersSize: -1,
+	}
+
+	done := func(body []byte) {
+		entry.Timings, entry.Time = tt.timings(time.Now())
+		entry.Response.BodySize = len(body)
+		entry.Response.Content = contentOf(r.redactor.Form(r.redactor.Body(body)), resp.Header.Get("Content-Type"))
+		r.add(entry)
+	}
+
+	if resp.Body == nil {
+		done(nil)
+		return resp, nil
+	}
+
+	resp.Body = &bodyRecorder{ReadCloser: resp.Body, done: done}
+
+	return resp, nil
+}
+
+// File returns the HAR document with the exchanges recorded so far.
+func (r *Recorder) File() *File {
+	r.mu.Lock()
+	entries := slices.Clone(r.entries)
+	r.mu.Unlock()
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].StartedDateTime.Before(entries[j].StartedDateTime)
+	})
+
+	return &File{
+		Log: Log{
+			Version: "1.2",
+			Creator: Creator{Name: "resto", Version: version()},
+			Entries: entries,
+		},
+	}
+}
+
+func (r *Recorder) add(e Entry) {
+	r.mu.Lock()
+	r.entries = append(r.entries, e)
+	r.mu.Unlock()
+}
+
+func (r *Recorder) requestOf(req *http.Request, body []byte) Request {
+	res := Request{
+		Method:      req.Method,
+		URL:         r.redactor.URL(req.URL),
+		HTTPVersion: "HTTP/1.1",
+		Cookies:     []NameValue{},
+		Headers:     nameValues(r.redactor.Header(req.Header)),
+		QueryString: []NameValue{},
+		HeadersSize: -1,
+		BodySize:    len(body),
+	}
+
+	for _, name := range sortedKeys(req.URL.Query()) {
+		for _, val := range req.URL.Query()[name] {
+			res.QueryString = append(res.QueryString, NameValue{Name: name, Value: val})
+		}
+	}
+
+	if len(body) > 0 {
+		mimeType := req.Header.Get("Content-Type")
+		if mimeType == "" {
+			mimeType = "application/octet-stream"
+		}
+		res.PostData = &PostData{MimeType: mimeType, Text: string(r.redactor.Form(r.redactor.Body(body)))}
+	}
+
+	return res
+}
+
+// bodyRecorder copies the response body while it is read and hands
+// it over at EOF or on Close, whichever comes first.
+type bodyRecorder struct {
+	io.ReadCloser
+	buf  bytes.Buffer
+	once sync.Once
+	done func([]byte)
+}
+
+func (br *bodyRecorder) Read(p []byte) (int, error) {
+	n, err := br.ReadCloser.Read(p)
+	br.buf.Write(p[:n])
+	if err == io.EOF {
+		br.once.Do(func() { br.done(br.buf.Bytes()) })
+	}
+	return n, err
+}
+
+func (br *bodyRecorder) Close() error {
+	err := br.ReadCloser.Close()
+	br.once.Do(func() { br.done(br.buf.Bytes()) })
+	return err
+}
+
+// traceTimes collects the phases of an exchange.
+type traceTimes struct {
+	mu sync.Mutex
+
+	start, gotConn, wrote, firstByte, headersAt time.Time
+
+	dnsStart, dnsDone         time.Time
+	connectStart, connectDone time.Time
+	tlsStart, tlsDone         time.Time
+}
+
+func (tt *traceTimes) clientTrace() *httptrace.ClientTrace {
+	set := func(t *time.Time) {
+		tt.mu.Lock()
+		if t.IsZero() {
+			*t = time.Now()
+		}
+		tt.mu.Unlock()
+	}
+
+	return &httptrace.ClientTrace{
+		GotConn:              func(httptrace.GotConnInfo) { set(&tt.gotConn) },
+		DNSStart:             func(httptrace.DNSStartInfo) { set(&tt.dnsStart) },
+		DNSDone:              func(httptrace.DNSDoneInfo) { set(&tt.dnsDone) },
+		ConnectStart:         func(string, string) { set(&tt.connectStart) },
+		ConnectDone:          func(string, string, error) { set(&tt.connectDone) },
+		TLSHandshakeStart:    func() { set(&tt.tlsStart) },
+		TLSHandshakeDone:     func(tls.ConnectionState, error) { set(&tt.tlsDone) },
+		WroteRequest:         func(httptrace.WroteRequestInfo) { set(&tt.wrote) },
+		GotFirstResponseByte: func() { set(&tt.firstByte) },
+	}
+}
+
+func (tt *traceTimes) headers(at time.Time) {
+	tt.mu.Lock()
+	tt.headersAt = at
+	tt.mu.Unlock()
+}
+
+// timings returns the HAR timings and the total time of the exchange
+// completed at end. Phases that did not happen (e.g. on a reused
+// connection) are reported as -1.
+func (tt *traceTimes) timings(end time.Time) (Timings, float64) {
+	tt.mu.Lock()
+	defer tt.mu.Unlock()
+
+	span := func(from, to time.Time) float64 {
+		if from.IsZero() || to.IsZero() {
+			return -1
+		}
+		return millis(to.Sub(from))
+	}
+
+	res := Timings{
+		Blocked: -1,
+		DNS:     span(tt.dnsStart, tt.dnsDone),
+		Connect: span(tt.connectStart, tt.connectDone),
+		SSL:     span(tt.tlsStart, tt.tlsDone),
+	}
+
+	sent := firstOf(tt.wrote, tt.gotConn, tt.start)
+	res.Send = max(span(firstOf(tt.gotConn, tt.start), sent), 0)
+
+	firstByte := firstOf(tt.firstByte, tt.headersAt, end)
+	res.Wait = max(span(sent, firstByte), 0)
+	res.Receive = max(span(firstByte, end), 0)
+
+	return res, millis(end.Sub(tt.start))
+}
+
+func firstOf(times ...time.Time) time.Time {
+	for _, el := range times {
+		if !el.IsZero() {
+			return el
+		}
+	}
+	return time.Time{}
+}
+
+func sortedKeys[M ~map[string][]string](m M) []string {
+	res := make([]string, 0, len(m))
+	for k := range m {
+		res = append(res, k)
+	}
+	sort.Strings(res)
+	return res
+}
+
+func version() string {
+	if bi, ok := debug.ReadBuildInfo(); ok && bi.Main.Version != "" {
+		return bi.Main.Version
+	}
+	return "(devel)"
+}
@@ -0,0 +1,108 @@
+package har
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"syscall"
+)
+
+// Replayer is an http.RoundTripper that serves the responses of a HAR
+// document instead of sending the requests.
+//
+// Requests are matched by method and URL; when the same request was
+// recorded more than once (e.g. every attempt of a retry loop), the
+// recorded responses are served in order and the last one is repeated.
+type Replayer struct {
+	mu      sync.Mutex
+	entries map[string][]Entry
+	served  map[string]int
+}
+
+// NewReplayer returns a Replayer serving the entries of f.
+func NewReplayer(f *File) *Replayer {
+	res := &Replayer{
+		entries: map[string][]Entry{},
+		served:  map[string]int{},
+	}
+
+	for _, el := range f.Log.Entries {
+		key := replayKey(el.Request.Method, el.Request.URL)
+		res.entries[key] = append(res.entries[key], el)
+	}
+
+	return res
+}
+
+func (r *Replayer) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Body != nil {
+		io.Copy(io.Discard, req.Body)
+		req.Body.Close()
+	}
+
+	key := replayKey(req.Method, req.URL.String())
+
+	r.mu.Lock()
+	list := r.entries[key]
+	idx := min(r.served[key], len(list)-1)
+	r.served[key]++
+	r.mu.Unlock()
+
+	if len(list) == 0 {
+		return nil, fmt.Errorf("har: no recorded response for %s", key)
+	}
+
+	entry := list[idx]
+	if entry.Error != "" {
+		return nil, replayedError(entry.Error)
+	}
+
+	body, err := entry.Response.Content.Body()
+	if err != nil {
+		return nil, fmt.Errorf("har: invalid recorded body for %s: %w", key, err)
+	}
+
+	header := http.Header{}
+	for _, el := range entry.Response.Headers {
+		header.Add(el.Name, el.Value)
+	}
+
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", entry.Response.Status, entry.Response.StatusText),
+		StatusCode:    entry.Response.Status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}, nil
+}
+
+func replayKey(method, uri string) string {
+	return strings.ToUpper(method) + " " + uri
+}
+
+// replayedError rebuilds a recorded transport error, keeping the
+// causes the retry conditions (connect-error, timeout) look for.
+func replayedError(msg string) error {
+	var cause error
+	switch {
+	case strings.Contains(msg, "connection refused"):
+		cause = syscall.ECONNREFUSED
+	case strings.Contains(msg, "connection reset"):
+		cause = syscall.ECONNRESET
+	case strings.Contains(msg, "deadline exceeded"), strings.Contains(msg, "timeout"):
+		cause = context.DeadlineExceeded
+	default:
+		return errors.New(msg)
+	}
+
+	return fmt.Errorf("%s: %w", msg, cause)
+}
@@ -0,0 +1,230 @@
+package restclient
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/lucasepe/resto/internal/util/retry"
+)
+
+// OperationOptions configures how the long-running operation
+// started by an Async request is polled.
+type OperationOptions struct {
+	Retrier  retry.Retrier
+	Strategy retry.Strategy
+	// Clock tells the time the Retry-After dates are relative to;
+	// retry.RealClock when nil.
+	Clock retry.Clock
+}
+
+// OperationError is returned when the long-running
+// operation ends in a failed or canceled state.
+type OperationError struct {
+	Status string
+}
+
+func (e *OperationError) Error() string {
+	return fmt.Sprintf("async operation ended with status %q", e.Status)
+}
+
+// operationHeaders point to the status of the operation,
+// in order of preference.
+var operationHeaders = []string{"Azure-AsyncOperation", "Operation-Location", "Location"}
+
+// doAsync sends the request once and, when the server answers 202 Accepted
+// with the URL of the operation status, polls it until the operation ends;
+// then the resulting resource is fetched and written.
+//
+// Any other response is written as is.
+func (hc *restClientImpl) doAsync(ctx context.Context, cli *http.Client, call *http.Request, streams IOStreams) error {
+	respo, err := cli.Do(call.WithContext(retry.WithoutCondition(ctx)))
+	if err != nil {
+		return err
+	}
+	defer respo.Body.Close()
+
+	var header, target string
+	for _, el := range operationHeaders {
+		if target = respo.Header.Get(el); target != "" {
+			header = el
+			break
+		}
+	}
+
+	if respo.StatusCode != http.StatusAccepted || target == "" {
+		return hc.writeResponse(ctx, cli, call, respo, streams)
+	}
+	io.Copy(io.Discard, respo.Body)
+
+	target, err = resolveURL(call.URL.String(), target)
+	if err != nil {
+		return fmt.Errorf("invalid %s header: %w", header, err)
+	}
+
+	body, state, err := hc.pollOperation(ctx, cli, target, streams)
+	if err != nil {
+		return err
+	}
+
+	result, err := hc.resultURL(call, respo.Header, header, body, state)
+	if err != nil {
+		return err
+	}
+
+	out := streams.Out
+	if out == nil {
+		out = io.Discard
+	}
+
+	// the operation ended with the resource itself
+	if result == "" {
+		return hc.writeBody(out, body, hc.output)
+	}
+
+	req, err := hc.getRequest(ctx, result)
+	if err != nil {
+		return err
+	}
+
+	res, err := cli.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	return hc.writeResponse(ctx, cli, req, res, streams)
+}
+
+// pollOperation requests the operation status until it reaches a
+// terminal state, waiting as set by the Retrier, the Strategy and
+// the Retry-After header. It returns the last status document and
+// the operation state, empty when the document is not an operation.
+func (hc *restClientImpl) pollOperation(ctx context.Context, cli *http.Client, uri string, streams IOStreams) (body []byte, state string, err error) {
+	err = hc.operation.Retrier.Retry(ctx, hc.operation.Strategy, func() (bool, error) {
+		req, err := hc.getRequest(ctx, uri)
+		if err != nil {
+			return false, err
+		}
+
+		res, err := cli.Do(req)
+		if err != nil {
+			return false, err
+		}
+
+		var buf bytes.Buffer
+		if err := dumpResponse(res, &buf, streams.Err); err != nil {
+			return false, err
+		}
+		body = buf.Bytes()
+
+		var done, failed bool
+		state, done, failed = operationStatus(res.StatusCode, body)
+		if failed {
+			if streams.Err != nil {
+				fmt.Fprintln(streams.Err, string(body))
+			}
+			return true, &OperationError{Status: state}
+		}
+
+		if !done {
+			if delay, ok := retry.RetryAfter(res.Header, hc.operation.Clock.Now()); ok {
+				return false, retry.After(delay)
+			}
+		}
+
+		return done, nil
+	})
+
+	return body, state, err
+}
+
+// resultURL returns the URL of the resource produced by the operation,
+// empty when the last status document is the resource itself:
+//   - the resourceLocation field of the status document (Azure)
+//   - the Location header of the 202 response, when the operation was
+//     followed through another header
+//   - the request URL, for PUT and PATCH requests
+func (hc *restClientImpl) resultURL(call *http.Request, accepted http.Header, header string, body []byte, state string) (string, error) {
+	if state == "" {
+		return "", nil
+	}
+
+	var doc struct {
+		ResourceLocation string `json:"resourceLocation"`
+	}
+	json.Unmarshal(body, &doc)
+
+	base := call.URL.String()
+	switch {
+	case doc.ResourceLocation != "":
+		return resolveURL(base, doc.ResourceLocation)
+	case header != "Location" && accepted.Get("Location") != "":
+		return resolveURL(base, accepted.Get("Location"))
+	case call.Method == http.MethodPut || call.Method == http.MethodPatch:
+		return base, nil
+	default:
+		return "", nil
+	}
+}
+
+// getRequest returns a GET request for uri with the request headers,
+// the condition evaluated by the retry round tripper excluded.
+func (hc *restClientImpl) getRequest(ctx context.Context, uri string) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(retry.WithoutCondition(ctx), http.MethodGet, uri, nil)
+	if err != nil {
+		return nil, err
+	}
+	setHeaders(req, hc.requestHeaders...)
+	req.Header.Del("Content-Type")
+
+	return req, nil
+}
+
+// operationStatus tells whether the operation has ended, and how, from the
+// status document: the status (or state) field, as Azure does, or the done
+// field of Google long-running operations.
+//
+// A document that is not an operation ends the polling unless the
+// status code is still 202 Accepted (the Location header pattern).
+func operationStatus(statusCode int, body []byte) (state string, done, failed bool) {
+	var doc struct {
+		Status *string          `json:"status"`
+		State  *string          `json:"state"`
+		Done   *bool            `json:"done"`
+		Error  *json.RawMessage `json:"error"`
+	}
+	if json.Unmarshal(body, &doc) != nil {
+		return "", statusCode != http.StatusAccepted, false
+	}
+
+	switch {
+	case doc.Done != nil && !*doc.Done:
+		return "Running", false, false
+	case doc.Done != nil && doc.Error != nil && string(*doc.Error) != "null":
+		return "Failed", true, true
+	case doc.Done != nil:
+		return "Succeeded", true, false
+	case doc.Status != nil:
+		state = *doc.Status
+	case doc.State != nil:
+		state = *doc.State
+	}
+
+	switch strings.ToLower(state) {
+	case "succeeded", "success", "completed", "done":
+		return state, true, false
+	case "failed", "canceled", "cancelled", "error":
+		return state, true, true
+	case "notstarted", "accepted", "queued", "pending", "running", "inprogress", "in_progress",
+		"creating", "updating", "deleting":
+		return state, false, false
+	}
+
+	// not an operation (or an unknown state)
+	return "", statusCode != http.StatusAccepted, false
+}
@@ -0,0 +1,184 @@
+package restclient
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/lucasepe/resto/internal/util/retry"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOperationStatus(t *testing.T) {
+	tests := []struct {
+		name       string
+		statusCode int
+		body       string
+		wantState  string
+		wantDone   bool
+		wantFailed bool
+	}{
+		{"azure in progress", 200, `{"status":"InProgress"}`, "InProgress", false, false},
+		{"azure succeeded", 200, `{"status":"Succeeded"}`, "Succeeded", true, false},
+		{"azure canceled", 200, `{"status":"Canceled"}`, "Canceled", true, true},
+		{"state field", 200, `{"state":"RUNNING"}`, "RUNNING", false, false},
+		{"google running", 200, `{"name":"op-1","done":false}`, "Running", false, false},
+		{"google done", 200, `{"name":"op-1","done":true,"response":{}}`, "Succeeded", true, false},
+		{"google error", 200, `{"name":"op-1","done":true,"error":{"code":3}}`, "Failed", true, true},
+		{"location pending", 202, ``, "", false, false},
+		{"location resource", 200, `{"id":"42","status":{"phase":"ready"}}`, "", true, false},
+		{"unknown state still accepted", 202, `{"status":"warming"}`, "", false, false},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			state, done, failed := operationStatus(tc.statusCode, []byte(tc.body))
+			assert.Equal(t, tc.wantState, state)
+			assert.Equal(t, tc.wantDone, done)
+			assert.Equal(t, tc.wantFailed, failed)
+		})
+	}
+}
+
+func TestRESTClient_DoAsync(t *testing.T) {
+	tests := []struct {
+		name    string
+		method  string
+		accept  func(w http.ResponseWriter)
+		poll    []string
+		want    string
+		wantErr bool
+		calls   []string
+		waits   []time.Duration
+	}{
+		{
+			name:   "azure async operation on put",
+			method: http.MethodPut,
+			accept: func(w http.ResponseWriter) {
+				w.Header().Set("Azure-AsyncOperation", "/operations/1")
+			},
+			poll:  []string{`{"status":"InProgress"}`, `{"status":"Succeeded"}`},
+			want:  `{"id":"vnet"}`,
+			calls: []string{"PUT /vnets/demo", "GET /operations/1", "GET /operations/1", "GET /vnets/demo"},
+			waits: []time.Duration{time.Millisecond},
+		},
+		{
+			name:   "operation location with resource location",
+			method: http.MethodPost,
+			accept: func(w http.ResponseWriter) {
+				w.Header().Set("Operation-Location", "/operations/1")
+			},
+			poll:  []string{`{"status":"Running"}`, `{"status":"Succeeded","resourceLocation":"/results/7"}`},
+			want:  `{"id":"result"}`,
+			calls: []string{"POST /vnets/demo", "GET /operations/1", "GET /operations/1", "GET /results/7"},
+			waits: []time.Duration{time.Millisecond},
+		},
+		{
+			name:   "location until the resource",
+			method: http.MethodPost,
+			accept: func(w http.ResponseWriter) {
+				w.Header().Set("Location", "/operations/1")
+			},
+			poll:  []string{"", `{"id":"created"}`},
+			want:  `{"id":"created"}`,
+			calls: []string{"POST /vnets/demo", "GET /operations/1", "GET /operations/1"},
+			waits: []time.Duration{2 * time.Second},
+		},
+		{
+			name:   "failed operation",
+			method: http.MethodPost,
+			accept: func(w http.ResponseWriter) {
+				w.Header().Set("Operation-Location", "/operations/1")
+			},
+			poll:    []string{`{"status":"Failed"}`},
+			wantErr: true,
+			calls:   []string{"POST /vnets/demo", "GET /operations/1"},
+		},
+		{
+			name:   "synchronous response",
+			method: http.MethodPut,
+			accept: nil,
+			want:   `{"id":"vnet"}`,
+			calls:  []string{"PUT /vnets/demo"},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var (
+				mu    sync.Mutex
+				calls []string
+				polls int
+			)
+
+			clock := retry.NewFakeClock(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				mu.Lock()
+				defer mu.Unlock()
+				calls = append(calls, r.Method+" "+r.URL.Path)
+
+				switch {
+				case r.Method != http.MethodGet && tc.accept != nil:
+					tc.accept(w)
+					w.WriteHeader(http.StatusAccepted)
+				case r.Method != http.MethodGet, r.URL.Path == "/vnets/demo":
+					io.WriteString(w, `{"id":"vnet"}`)
+				case r.URL.Path == "/results/7":
+					io.WriteString(w, `{"id":"result"}`)
+				default:
+					body := tc.poll[min(polls, len(tc.poll)-1)]
+					polls++
+					if body == "" {
+						w.Header().Set("Retry-After", clock.Now().Add(2*time.Second).Format(http.TimeFormat))
+						w.WriteHeader(http.StatusAccepted)
+						return
+					}
+					io.WriteString(w, body)
+				}
+			}))
+			defer srv.Close()
+
+			var (
+				out, errBuf bytes.Buffer
+				err         error
+			)
+			clock.Run(func() {
+				err = New(RequestOptions{
+					BaseURL: srv.URL,
+					Path:    "/vnets/demo",
+					Method:  tc.method,
+					Async:   true,
+					Operation: OperationOptions{
+						Strategy: retry.Exp(),
+						Retrier: retry.NewRetrier(retry.RetryOptions{
+							InitialDelay: time.Millisecond,
+							MaxDelay:     time.Minute,
+							MaxAttempts:  5,
+							Clock:        clock,
+						}),
+						Clock: clock,
+					},
+				}).Do(context.Background(), srv.Client(), IOStreams{Out: &out, Err: &errBuf})
+			})
+
+			assert.Equal(t, tc.calls, calls)
+			assert.Equal(t, tc.waits, clock.Waits())
+			if tc.wantErr {
+				var opErr *OperationError
+				require.ErrorAs(t, err, &opErr)
+				assert.Equal(t, "Failed", opErr.Status)
+				assert.Contains(t, errBuf.String(), `{"status":"Failed"}`)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.want, out.String())
+		})
+	}
+}
@@ -0,0 +1,89 @@
+package restclient
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"os"
+)
+
+// maxMemoryBody is the size above which the request
+// body is spooled to a temporary file.
+const maxMemoryBody = 1 << 20
+
+// bodySpool holds a copy of the request body, so that it can be sent
+// again by every retry attempt (see http.Request.GetBody).
+type bodySpool struct {
+	data []byte
+	file *os.File
+	size int64
+}
+
+// newBodySpool reads src until EOF: up to threshold bytes are kept in
+// memory, larger bodies are written to a temporary file.
+func newBodySpool(src io.Reader, threshold int64) (*bodySpool, error) {
+	var buf bytes.Buffer
+	n, err := io.CopyN(&buf, src, threshold+1)
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+
+	if n <= threshold {
+		return &bodySpool{data: buf.Bytes(), size: n}, nil
+	}
+
+	file, err := os.CreateTemp("", "resto-body-*")
+	if err != nil {
+		return nil, err
+	}
+	res := &bodySpool{file: file}
+
+	res.size, err = io.Copy(file, io.MultiReader(&buf, src))
+	if err != nil {
+		res.Close()
+		return nil, err
+	}
+
+	return res, nil
+}
+
+// Open returns a new reader of the whole body.
+func (bs *bodySpool) Open() (io.ReadCloser, error) {
+	switch {
+	case bs.size == 0:
+		return http.NoBody, nil
+	case bs.file != nil:
+		return io.NopCloser(io.NewSectionReader(bs.file, 0, bs.size)), nil
+	default:
+		return io.NopCloser(bytes.NewReader(bs.data)), nil
+	}
+}
+
+// Close removes the temporary file, if any.
+func (bs *bodySpool) Close() error {
+	if bs.file == nil {
+		return nil
+	}
+
+	err := bs.file.Close()
+	if rerr := os.Remove(bs.file.Name()); err == nil {
+		err = rerr
+	}
+	bs.file = nil
+
+	return err
+}
+
+// setBody makes the spooled body the request body.
+func setBody(req *http.Request, bs *bodySpool) error {
+	body, err := bs.Open()
+	if err != nil {
+		return err
+	}
+
+	req.Body = body
+	req.GetBody = bs.Open
+	req.ContentLength = bs.size
+
+	return nil
+}
@@ -0,0 +1,115 @@
+package restclient
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/lucasepe/resto/internal/util/retry"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBodySpool(t *testing.T) {
+	tests := []struct {
+		name      string
+		body      string
+		threshold int64
+		inFile    bool
+	}{
+		{name: "empty", body: "", threshold: 8},
+		{name: "in memory", body: `{"a":1}`, threshold: 8},
+		{name: "spooled to file", body: `{"name":"resto","tags":["a","b"]}`, threshold: 8, inFile: true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			bs, err := newBodySpool(strings.NewReader(tc.body), tc.threshold)
+			require.NoError(t, err)
+			assert.Equal(t, int64(len(tc.body)), bs.size)
+			assert.Equal(t, tc.inFile, bs.file != nil)
+
+			var name string
+			if bs.file != nil {
+				name = bs.file.Name()
+			}
+
+			// every reader returns the whole body
+			for range 2 {
+				rc, err := bs.Open()
+				require.NoError(t, err)
+				got, err := io.ReadAll(rc)
+				require.NoError(t, err)
+				assert.Equal(t, tc.body, string(got))
+			}
+
+			require.NoError(t, bs.Close())
+			if name != "" {
+				_, err := os.Stat(name)
+				assert.True(t, os.IsNotExist(err), "temporary file not removed")
+			}
+		})
+	}
+}
+
+func TestRESTClient_DoRetriesWithSameBody(t *testing.T) {
+	payload := []byte(`{"name":"resto","replicas":3}`)
+
+	var (
+		mu     sync.Mutex
+		bodies []string
+	)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		bin, _ := io.ReadAll(r.Body)
+
+		mu.Lock()
+		bodies = append(bodies, string(bin))
+		n := len(bodies)
+		mu.Unlock()
+
+		if n < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer ts.Close()
+
+	// a file, as with --file: it can be read only once
+	filename := filepath.Join(t.TempDir(), "body.json")
+	require.NoError(t, os.WriteFile(filename, payload, 0600))
+	src, err := os.Open(filename)
+	require.NoError(t, err)
+	defer src.Close()
+
+	retryOn, err := retry.ParseRetryOn("5xx")
+	require.NoError(t, err)
+
+	cli := &http.Client{
+		Transport: retry.NewRoundTripper(http.DefaultTransport, retry.RoundTripperOptions{
+			RetryOn:  retryOn,
+			Strategy: retry.Jittered(0),
+			Retrier: retry.NewRetrier(retry.RetryOptions{
+				InitialDelay: time.Millisecond,
+				MaxDelay:     time.Millisecond,
+				MaxAttempts:  5,
+			}),
+		}),
+	}
+
+	var out bytes.Buffer
+	err = New(RequestOptions{BaseURL: ts.URL, Method: http.MethodPost}).
+		Do(context.Background(), cli, IOStreams{In: src, Out: &out, Err: io.Discard})
+	require.NoError(t, err)
+
+	want := string(payload)
+	assert.Equal(t, []string{want, want, want}, bodies)
+}
@@ -1,8 +1,13 @@
 package restclient
 
 import (
+	"log/slog"
+	"net/http"
 	"os"
 	"strconv"
+	"time"
+
+	"github.com/lucasepe/resto/internal/util/redact"
 )
 
 func ConfigFromEnv() (res Config) {
@@ -18,6 +23,18 @@ func ConfigFromEnv() (res Config) {
 		res.Token = string(v)
 	}
 
+	if v, ok := os.LookupEnv(tokenFileEnv); ok {
+		res.TokenFile = v
+	}
+
+	if v, ok := os.LookupEnv(tokenCommandEnv); ok {
+		res.TokenCommand = v
+	}
+
+	if v, ok := os.LookupEnv(tokenCommandTTLEnv); ok {
+		res.TokenCommandTTL, _ = time.ParseDuration(v)
+	}
+
 	if v, ok := os.LookupEnv(usernameEnv); ok {
 		res.Username = string(v)
 	}
@@ -38,6 +55,42 @@ func ConfigFromEnv() (res Config) {
 		res.ClientCertificateData = string(v)
 	}
 
+	if v, ok := os.LookupEnv(caFileEnv); ok {
+		res.CertificateAuthorityFile = v
+	}
+
+	if v, ok := os.LookupEnv(certFileEnv); ok {
+		res.ClientCertificateFile = v
+	}
+
+	if v, ok := os.LookupEnv(keyFileEnv); ok {
+		res.ClientKeyFile = v
+	}
+
+	if v, ok := os.LookupEnv(systemCAEnv); ok {
+		res.SystemRoots, _ = strconv.ParseBool(v)
+	}
+
+	if v, ok := os.LookupEnv(oauthTokenURLEnv); ok {
+		res.OAuthTokenURL = v
+	}
+
+	if v, ok := os.LookupEnv(oauthClientIDEnv); ok {
+		res.OAuthClientID = v
+	}
+
+	if v, ok := os.LookupEnv(oauthClientSecretEnv); ok {
+		res.OAuthClientSecret = v
+	}
+
+	if v, ok := os.LookupEnv(oauthScopesEnv); ok {
+		res.OAuthScopes = v
+	}
+
+	if v, ok := os.LookupEnv(oauthAudienceEnv); ok {
+		res.OAuthAudience = v
+	}
+
 	if v, ok := os.LookupEnv(verboseEnv); ok {
 		res.Verbose, _ = strconv.ParseBool(string(v))
 	}
@@ -55,16 +108,51 @@ type Config struct {
 	CertificateAuthorityData string
 	ClientCertificateData    string
 	ClientKeyData            string
-	Token                    string
-	Username                 string
-	Password                 string
-	Verbose                  bool
-	Insecure                 bool
+	// CertificateAuthorityFile, ClientCertificateFile and ClientKeyFile
+	// are PEM files read when the corresponding *Data field is empty.
+	CertificateAuthorityFile string
+	ClientCertificateFile    string
+	ClientKeyFile            string
+	// SystemRoots adds the certificate authority to the system
+	// roots instead of replacing them.
+	SystemRoots bool
+	Token       string
+	// TokenFile and TokenCommand are alternatives to Token: the file is
+	// read again when modified, the command output is reused for at
+	// most TokenCommandTTL.
+	TokenFile       string
+	TokenCommand    string
+	TokenCommandTTL time.Duration
+	Username        string
+	Password        string
+	// OAuthTokenURL, OAuthClientID and OAuthClientSecret configure
+	// the OAuth2 client credentials grant; OAuthScopes is a comma or
+	// space separated list.
+	OAuthTokenURL     string
+	OAuthClientID     string
+	OAuthClientSecret string
+	OAuthScopes       string
+	OAuthAudience     string
+	Verbose           bool
+	Insecure          bool
+	// WrapTransport, if set, wraps (or replaces) the innermost transport,
+	// below the verbose and authentication round trippers; it sees every
+	// single attempt exactly as sent.
+	WrapTransport func(rt http.RoundTripper) http.RoundTripper
+	// Logger, if set, receives the verbose output as debug records
+	// instead of the curl-like dumps.
+	Logger *slog.Logger
+	// Redactor hides the secrets from the verbose output and the logs;
+	// nil shows everything.
+	Redactor *redact.Redactor
+	// Stream tells the verbose output that the response bodies are
+	// consumed incrementally (--watch), so they are echoed as read.
+	Stream bool
 }
 
 // HasCA returns whether the configuration has a certificate authority or not.
 func (ep *Config) HasCA() bool {
-	return len(ep.CertificateAuthorityData) > 0
+	return len(ep.CertificateAuthorityData) > 0 || len(ep.CertificateAuthorityFile) > 0
 }
 
 // HasBasicAuth returns whether the configuration has basic authentication or not.
@@ -74,12 +162,28 @@ func (ep *Config) HasBasicAuth() bool {
 
 // HasTokenAuth returns whether the configuration has token authentication or not.
 func (ep *Config) HasTokenAuth() bool {
-	return len(ep.Token) != 0
+	return ep.tokenSources() > 0
+}
+
+// tokenSources returns how many of Token, TokenFile and TokenCommand are set.
+func (ep *Config) tokenSources() (n int) {
+	for _, el := range []string{ep.Token, ep.TokenFile, ep.TokenCommand} {
+		if len(el) != 0 {
+			n++
+		}
+	}
+	return n
+}
+
+// HasOAuth returns whether the configuration has OAuth2 client credentials or not.
+func (ep *Config) HasOAuth() bool {
+	return len(ep.OAuthTokenURL) != 0 && len(ep.OAuthClientID) != 0
 }
 
 // HasCertAuth returns whether the configuration has certificate authentication or not.
 func (ep *Config) HasCertAuth() bool {
-	return len(ep.ClientCertificateData) != 0 && len(ep.ClientKeyData) != 0
+	return (len(ep.ClientCertificateData) != 0 || len(ep.ClientCertificateFile) != 0) &&
+		(len(ep.ClientKeyData) != 0 || len(ep.ClientKeyFile) != 0)
 }
 
 const (
@@ -91,6 +195,21 @@ const (
 	clientCertEnv = "CERT"
 	clientKeyEnv  = "CERT_KEY"
 	caEnv         = "CA_CERT"
+	caFileEnv     = "CA_FILE"
+	certFileEnv   = "CERT_FILE"
+	keyFileEnv    = "KEY_FILE"
+	systemCAEnv   = "SYSTEM_CA"
 	insecureEnv   = "INSECURE"
 	verboseEnv    = "VERBOSE"
+	kubeconfigEnv = "KUBECONFIG"
+
+	tokenFileEnv       = "TOKEN_FILE"
+	tokenCommandEnv    = "TOKEN_COMMAND"
+	tokenCommandTTLEnv = "TOKEN_COMMAND_TTL"
+
+	oauthTokenURLEnv     = "OAUTH_TOKEN_URL"
+	oauthClientIDEnv     = "OAUTH_CLIENT_ID"
+	oauthClientSecretEnv = "OAUTH_CLIENT_SECRET"
+	oauthScopesEnv       = "OAUTH_SCOPES"
+	oauthAudienceEnv     = "OAUTH_AUDIENCE"
 )
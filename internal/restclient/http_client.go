@@ -14,32 +14,60 @@ func HTTPClientForConfig(cfg Config) (*http.Client, error) {
 		}, err
 	}
 
+	if cfg.WrapTransport != nil {
+		rt = cfg.WrapTransport(rt)
+	}
+
+	debug := func(msg string) {
+		if cfg.Logger != nil {
+			cfg.Logger.Debug(msg)
+			return
+		}
+		log.Println(msg)
+	}
+
 	if cfg.Verbose {
-		log.Println("using verbose roundtripper")
+		debug("using verbose roundtripper")
 
 		rt = &verboseRoundTripper{
-			v:    true,
-			next: rt,
+			v:        true,
+			next:     rt,
+			logger:   cfg.Logger,
+			redactor: cfg.Redactor,
+			stream:   cfg.Stream,
 		}
 	}
 
 	// Set authentication wrappers
 	switch {
+	case cfg.tokenSources() > 1:
+		return nil, fmt.Errorf("only one of bearer token, token file or token command may be set")
+
 	case cfg.HasBasicAuth() && cfg.HasTokenAuth():
 		return nil, fmt.Errorf("username/password or bearer token may be set, but not both")
 
+	case cfg.HasOAuth() && (cfg.HasBasicAuth() || cfg.HasTokenAuth()):
+		return nil, fmt.Errorf("oauth2 client credentials cannot be used with username/password or bearer token")
+
+	case cfg.HasOAuth():
+		if cfg.Verbose {
+			debug("using oauth2 client credentials roundtripper")
+		}
+		rt = newOAuth2RoundTripper(&cfg, rt)
+
 	case cfg.HasTokenAuth():
 		if cfg.Verbose {
-			log.Println("using bearer auth roundtripper")
+			debug("using bearer auth roundtripper")
 		}
 		rt = &bearerAuthRoundTripper{
 			bearer: cfg.Token,
+			source: newTokenSource(&cfg),
 			next:   rt,
 		}
 
 	case cfg.HasBasicAuth():
 		if cfg.Verbose {
-			log.Println("using basic auth roundtripper")
+			debug("using basic auth roundtripper")
 		}
 		rt = &basicAuthRoundTripper{
 			username: cfg.Username,
@@ -30,6 +30,21 @@ func TestHTTPClientForConfig(t *testing.T) {
 			cfg:       Config{Username: "user", Password: "pass", Token: "token"},
 			expectErr: true,
 		},
+		{
+			name:      "valid config with token command",
+			cfg:       Config{TokenCommand: "echo token"},
+			expectErr: false,
+		},
+		{
+			name:      "invalid config with token and token file",
+			cfg:       Config{Token: "token", TokenFile: "/var/run/token"},
+			expectErr: true,
+		},
+		{
+			name:      "invalid config with oauth2 and bearer token",
+			cfg:       Config{OAuthTokenURL: "http://localhost/token", OAuthClientID: "id", Token: "token"},
+			expectErr: true,
+		},
 	}
 
 	for _, tc := range tests {
@@ -0,0 +1,291 @@
+package restclient
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"gopkg.in/yaml.v3"
+)
+
+// DefaultKubeconfig returns the kubeconfig file path to use when none is
+// explicitly given: $KUBECONFIG (possibly a list of paths) or ~/.kube/config.
+func DefaultKubeconfig() string {
+	if v, ok := os.LookupEnv(kubeconfigEnv); ok && v != "" {
+		return v
+	}
+
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+
+	return filepath.Join(home, ".kube", "config")
+}
+
+// ConfigFromKubeconfig parses the kubeconfig file and returns the Config
+// for the given context name. If contextName is empty the kubeconfig
+// current-context is used.
+//
+// As kubectl does, filename may be a list of paths (see filepath.SplitList):
+// the files are merged, the first one defining a context, cluster, user or
+// the current-context wins, and missing files are skipped.
+//
+// Certificate and key file paths are resolved relative to the kubeconfig
+// defining them and their contents are loaded into the corresponding *Data
+// fields; the token file is instead read on every request (see Config.TokenFile).
+// Users authenticating through exec plugins or auth providers are not supported.
+func ConfigFromKubeconfig(filename, contextName string) (Config, error) {
+	kc, err := loadKubeconfig(filename)
+	if err != nil {
+		return Config{}, err
+	}
+
+	if contextName == "" {
+		contextName = kc.CurrentContext
+	}
+	if contextName == "" {
+		return Config{}, fmt.Errorf("no context specified and no current-context set in kubeconfig %q", filename)
+	}
+
+	ctx, ok := kc.context(contextName)
+	if !ok {
+		return Config{}, fmt.Errorf("context %q not found in kubeconfig %q", contextName, filename)
+	}
+
+	cluster, ok := kc.cluster(ctx.Cluster)
+	if !ok {
+		return Config{}, fmt.Errorf("cluster %q not found in kubeconfig %q", ctx.Cluster, filename)
+	}
+
+	res := Config{
+		ServerURL: cluster.Server,
+		ProxyURL:  cluster.ProxyURL,
+		Insecure:  cluster.InsecureSkipTLSVerify,
+	}
+
+	res.CertificateAuthorityData, err = dataOrFile(cluster.CertificateAuthorityData, cluster.CertificateAuthority, cluster.dir)
+	if err != nil {
+		return Config{}, fmt.Errorf("unable to read certificate authority: %w", err)
+	}
+
+	// A context may legitimately have no user (e.g. anonymous access).
+	if ctx.User == "" {
+		return res, nil
+	}
+
+	user, ok := kc.user(ctx.User)
+	if !ok {
+		return Config{}, fmt.Errorf("user %q not found in kubeconfig %q", ctx.User, filename)
+	}
+
+	switch {
+	case user.Exec != nil:
+		return Config{}, fmt.Errorf("user %q: exec credential plugins are not supported", ctx.User)
+	case user.AuthProvider != nil:
+		return Config{}, fmt.Errorf("user %q: auth providers are not supported", ctx.User)
+	}
+
+	dir := user.dir
+
+	res.ClientCertificateData, err = dataOrFile(user.ClientCertificateData, user.ClientCertificate, dir)
+	if err != nil {
+		return Config{}, fmt.Errorf("unable to read client certificate: %w", err)
+	}
+
+	res.ClientKeyData, err = dataOrFile(user.ClientKeyData, user.ClientKey, dir)
+	if err != nil {
+		return Config{}, fmt.Errorf("unable to read client key: %w", err)
+	}
+
+	// the token file is read on every request, since it may be rotated
+	res.Token = user.Token
+	if res.Token == "" && user.TokenFile != "" {
+		res.TokenFile = resolvePath(user.TokenFile, dir)
+		if _, err := os.Stat(res.TokenFile); err != nil {
+			return Config{}, fmt.Errorf("unable to read token file: %w", err)
+		}
+	}
+
+	// as client-go does, a token wins over basic auth
+	if res.Token == "" && res.TokenFile == "" {
+		res.Username = user.Username
+		res.Password = user.Password
+	}
+
+	return res, nil
+}
+
+// loadKubeconfig reads and merges the kubeconfig files in the given path list.
+func loadKubeconfig(filename string) (*kubeconfig, error) {
+	paths := filepath.SplitList(filename)
+
+	var res kubeconfig
+	loaded := 0
+	for _, path := range paths {
+		if path == "" {
+			continue
+		}
+
+		src, err := os.ReadFile(path)
+		if errors.Is(err, fs.ErrNotExist) && len(paths) > 1 {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		var kc kubeconfig
+		if err := yaml.Unmarshal(src, &kc); err != nil {
+			return nil, fmt.Errorf("unable to parse kubeconfig %q: %w", path, err)
+		}
+		kc.setDir(filepath.Dir(path))
+
+		res.merge(kc)
+		loaded++
+	}
+
+	if loaded == 0 {
+		return nil, fmt.Errorf("no kubeconfig found in %q", filename)
+	}
+
+	return &res, nil
+}
+
+// dataOrFile returns the base64 encoded data if set, otherwise
+// it reads the given file and returns its content base64 encoded.
+func dataOrFile(data, filename, dir string) (string, error) {
+	if data != "" {
+		return data, nil
+	}
+
+	if filename == "" {
+		return "", nil
+	}
+
+	bin, err := os.ReadFile(resolvePath(filename, dir))
+	if err != nil {
+		return "", err
+	}
+
+	return base64.StdEncoding.EncodeToString(bin), nil
+}
+
+// resolvePath makes relative paths relative to dir,
+// as kubectl does for paths found in a kubeconfig file.
+func resolvePath(filename, dir string) string {
+	if filename == "" || filepath.IsAbs(filename) {
+		return filename
+	}
+
+	return filepath.Join(dir, filename)
+}
+
+type kubeconfig struct {
+	CurrentContext string `yaml:"current-context"`
+	Clusters       []struct {
+		Name    string            `yaml:"name"`
+		Cluster kubeconfigCluster `yaml:"cluster"`
+	} `yaml:"clusters"`
+	Users []struct {
+		Name string         `yaml:"name"`
+		User kubeconfigUser `yaml:"user"`
+	} `yaml:"users"`
+	Contexts []struct {
+		Name    string            `yaml:"name"`
+		Context kubeconfigContext `yaml:"context"`
+	} `yaml:"contexts"`
+}
+
+type kubeconfigCluster struct {
+	Server                   string `yaml:"server"`
+	ProxyURL                 string `yaml:"proxy-url"`
+	CertificateAuthority     string `yaml:"certificate-authority"`
+	CertificateAuthorityData string `yaml:"certificate-authority-data"`
+	InsecureSkipTLSVerify    bool   `yaml:"insecure-skip-tls-verify"`
+
+	// dir of the kubeconfig defining the cluster
+	dir string
+}
+
+type kubeconfigUser struct {
+	ClientCertificate     string `yaml:"client-certificate"`
+	ClientCertificateData string `yaml:"client-certificate-data"`
+	ClientKey             string `yaml:"client-key"`
+	ClientKeyData         string `yaml:"client-key-data"`
+	Token                 string `yaml:"token"`
+	TokenFile             string `yaml:"tokenFile"`
+	Username              string `yaml:"username"`
+	Password              string `yaml:"password" datapolicy:"password"`
+	Exec                  any    `yaml:"exec"`
+	AuthProvider          any    `yaml:"auth-provider"`
+
+	// dir of the kubeconfig defining the user
+	dir string
+}
+
+type kubeconfigContext struct {
+	Cluster string `yaml:"cluster"`
+	User    string `yaml:"user"`
+}
+
+func (kc *kubeconfig) setDir(dir string) {
+	for i := range kc.Clusters {
+		kc.Clusters[i].Cluster.dir = dir
+	}
+	for i := range kc.Users {
+		kc.Users[i].User.dir = dir
+	}
+}
+
+// merge adds the entries of other not defined yet.
+func (kc *kubeconfig) merge(other kubeconfig) {
+	if kc.CurrentContext == "" {
+		kc.CurrentContext = other.CurrentContext
+	}
+	for _, el := range other.Clusters {
+		if _, ok := kc.cluster(el.Name); !ok {
+			kc.Clusters = append(kc.Clusters, el)
+		}
+	}
+	for _, el := range other.Users {
+		if _, ok := kc.user(el.Name); !ok {
+			kc.Users = append(kc.Users, el)
+		}
+	}
+	for _, el := range other.Contexts {
+		if _, ok := kc.context(el.Name); !ok {
+			kc.Contexts = append(kc.Contexts, el)
+		}
+	}
+}
+
+func (kc *kubeconfig) context(name string) (kubeconfigContext, bool) {
+	for _, el := range kc.Contexts {
+		if el.Name == name {
+			return el.Context, true
+		}
+	}
+	return kubeconfigContext{}, false
+}
+
+func (kc *kubeconfig) cluster(name string) (kubeconfigCluster, bool) {
+	for _, el := range kc.Clusters {
+		if el.Name == name {
+			return el.Cluster, true
+		}
+	}
+	return kubeconfigCluster{}, false
+}
+
+func (kc *kubeconfig) user(name string) (kubeconfigUser, bool) {
+	for _, el := range kc.Users {
+		if el.Name == name {
+			return el.User, true
+		}
+	}
+	return kubeconfigUser{}, false
+}
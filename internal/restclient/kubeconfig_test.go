@@ -0,0 +1,228 @@
+package restclient
+
+import (
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const testKubeconfig = `apiVersion: v1
+kind: Config
+current-context: dev
+clusters:
+- name: dev-cluster
+  cluster:
+    server: https://dev.example.com:6443
+    certificate-authority: ca.crt
+- name: prod-cluster
+  cluster:
+    server: https://prod.example.com:6443
+    certificate-authority-data: cHJvZC1jYQ==
+    insecure-skip-tls-verify: true
+users:
+- name: dev-user
+  user:
+    client-certificate: client.crt
+    client-key-data: ZGV2LWtleQ==
+- name: prod-user
+  user:
+    tokenFile: token
+- name: both-user
+  user:
+    token: both-token
+    username: admin
+    password: secret
+- name: exec-user
+  user:
+    exec:
+      apiVersion: client.authentication.k8s.io/v1
+      command: aws
+- name: gcp-user
+  user:
+    auth-provider:
+      name: gcp
+contexts:
+- name: dev
+  context:
+    cluster: dev-cluster
+    user: dev-user
+- name: prod
+  context:
+    cluster: prod-cluster
+    user: prod-user
+- name: broken
+  context:
+    cluster: missing-cluster
+- name: both
+  context:
+    cluster: dev-cluster
+    user: both-user
+- name: exec
+  context:
+    cluster: dev-cluster
+    user: exec-user
+- name: gcp
+  context:
+    cluster: dev-cluster
+    user: gcp-user
+`
+
+func TestConfigFromKubeconfig(t *testing.T) {
+	dir := t.TempDir()
+
+	files := map[string]string{
+		"config":     testKubeconfig,
+		"ca.crt":     "dev-ca",
+		"client.crt": "dev-cert",
+		"token":      "prod-token\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	filename := filepath.Join(dir, "config")
+
+	b64 := func(s string) string {
+		return base64.StdEncoding.EncodeToString([]byte(s))
+	}
+
+	tests := []struct {
+		name    string
+		context string
+		want    Config
+		wantErr bool
+	}{
+		{
+			name: "current context with file paths",
+			want: Config{
+				ServerURL:                "https://dev.example.com:6443",
+				CertificateAuthorityData: b64("dev-ca"),
+				ClientCertificateData:    b64("dev-cert"),
+				ClientKeyData:            "ZGV2LWtleQ==",
+			},
+		},
+		{
+			name:    "explicit context with inline data and token file",
+			context: "prod",
+			want: Config{
+				ServerURL:                "https://prod.example.com:6443",
+				CertificateAuthorityData: "cHJvZC1jYQ==",
+				TokenFile:                filepath.Join(dir, "token"),
+				Insecure:                 true,
+			},
+		},
+		{
+			name:    "unknown context",
+			context: "nope",
+			wantErr: true,
+		},
+		{
+			name:    "context referencing a missing cluster",
+			context: "broken",
+			wantErr: true,
+		},
+		{
+			name:    "token wins over basic auth",
+			context: "both",
+			want: Config{
+				ServerURL:                "https://dev.example.com:6443",
+				CertificateAuthorityData: b64("dev-ca"),
+				Token:                    "both-token",
+			},
+		},
+		{
+			name:    "exec credential plugin",
+			context: "exec",
+			wantErr: true,
+		},
+		{
+			name:    "auth provider",
+			context: "gcp",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ConfigFromKubeconfig(filename, tt.context)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("unexpected error status: got err=%v, wantErr=%v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestConfigFromKubeconfig_Merge(t *testing.T) {
+	dir := t.TempDir()
+
+	const first = `current-context: dev
+contexts:
+- name: dev
+  context:
+    cluster: dev-cluster
+    user: dev-user
+users:
+- name: dev-user
+  user:
+    token: first-token
+`
+
+	const second = `current-context: prod
+clusters:
+- name: dev-cluster
+  cluster:
+    server: https://dev.example.com:6443
+    certificate-authority: ca.crt
+users:
+- name: dev-user
+  user:
+    token: second-token
+`
+
+	files := map[string]string{
+		"first":        first,
+		"other/second": second,
+		"other/ca.crt": "dev-ca",
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	filename := strings.Join([]string{
+		filepath.Join(dir, "first"),
+		filepath.Join(dir, "missing"),
+		filepath.Join(dir, "other", "second"),
+	}, string(filepath.ListSeparator))
+
+	got, err := ConfigFromKubeconfig(filename, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := Config{
+		ServerURL:                "https://dev.example.com:6443",
+		CertificateAuthorityData: base64.StdEncoding.EncodeToString([]byte("dev-ca")),
+		Token:                    "first-token",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+
+	if _, err := ConfigFromKubeconfig(filepath.Join(dir, "missing"), ""); err == nil {
+		t.Error("expected an error for a missing kubeconfig")
+	}
+}
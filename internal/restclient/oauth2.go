@@ -0,0 +1,180 @@
+package restclient
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// expiryDelta renews the access token a bit before it actually
+// expires, so that it does not expire in flight.
+const expiryDelta = 10 * time.Second
+
+// oauth2RoundTripper authenticates the requests with an access token
+// obtained with the OAuth2 client credentials grant (RFC 6749, 4.4).
+//
+// The token is cached until it expires and renewed transparently;
+// when the server answers 401 the token is renewed and the request
+// is sent once more.
+type oauth2RoundTripper struct {
+	tokenURL     string
+	clientID     string
+	clientSecret string `datapolicy:"password"`
+	scopes       []string
+	audience     string
+	next         http.RoundTripper
+	now          func() time.Time
+
+	mu     sync.Mutex
+	token  string `datapolicy:"token"`
+	expiry time.Time
+}
+
+func newOAuth2RoundTripper(cfg *Config, next http.RoundTripper) *oauth2RoundTripper {
+	return &oauth2RoundTripper{
+		tokenURL:     cfg.OAuthTokenURL,
+		clientID:     cfg.OAuthClientID,
+		clientSecret: cfg.OAuthClientSecret,
+		scopes:       strings.FieldsFunc(cfg.OAuthScopes, func(r rune) bool { return r == ',' || r == ' ' }),
+		audience:     cfg.OAuthAudience,
+		next:         next,
+		now:          time.Now,
+	}
+}
+
+func (rt *oauth2RoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	if len(req.Header.Get("Authorization")) != 0 {
+		return rt.next.RoundTrip(req)
+	}
+
+	token, err := rt.accessToken(req.Context(), "")
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := rt.next.RoundTrip(withBearer(req, token))
+	if err != nil || resp.StatusCode != http.StatusUnauthorized {
+		return resp, err
+	}
+
+	// the body has been consumed and cannot be sent again
+	if req.Body != nil && req.Body != http.NoBody && req.GetBody == nil {
+		return resp, nil
+	}
+
+	token, err = rt.accessToken(req.Context(), token)
+	if err != nil {
+		return resp, nil
+	}
+	io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+
+	retry := withBearer(req, token)
+	if req.GetBody != nil {
+		if retry.Body, err = req.GetBody(); err != nil {
+			return nil, err
+		}
+	}
+
+	return rt.next.RoundTrip(retry)
+}
+
+func withBearer(req *http.Request, token string) *http.Request {
+	res := cloneRequest(req)
+	res.Header.Set("Authorization", "Bearer "+token)
+	return res
+}
+
+// accessToken returns the cached token, unless it is expired or it is
+// the rejected one: then a new token is requested.
+func (rt *oauth2RoundTripper) accessToken(ctx context.Context, rejected string) (string, error) {
+	rt.mu.Lock()
+	defer rt.mu.Unlock()
+
+	valid := rt.token != "" && (rt.expiry.IsZero() || rt.now().Before(rt.expiry))
+	if valid && rt.token != rejected {
+		return rt.token, nil
+	}
+
+	token, expiresIn, err := rt.fetchToken(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	rt.token, rt.expiry = token, time.Time{}
+	if expiresIn > 0 {
+		rt.expiry = rt.now().Add(time.Duration(expiresIn)*time.Second - expiryDelta)
+	}
+
+	return rt.token, nil
+}
+
+// fetchToken requests a new access token to the token endpoint,
+// authenticating the client with HTTP Basic (client_secret_basic).
+func (rt *oauth2RoundTripper) fetchToken(ctx context.Context) (string, int64, error) {
+	form := url.Values{"grant_type": {"client_credentials"}}
+	if len(rt.scopes) > 0 {
+		form.Set("scope", strings.Join(rt.scopes, " "))
+	}
+	if rt.audience != "" {
+		form.Set("audience", rt.audience)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, rt.tokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", 0, fmt.Errorf("oauth2: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	req.SetBasicAuth(url.QueryEscape(rt.clientID), url.QueryEscape(rt.clientSecret))
+
+	resp, err := rt.next.RoundTrip(req)
+	if err != nil {
+		return "", 0, fmt.Errorf("oauth2: token request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return "", 0, fmt.Errorf("oauth2: unable to read token response: %w", err)
+	}
+
+	var res struct {
+		AccessToken      string      `json:"access_token"`
+		TokenType        string      `json:"token_type"`
+		ExpiresIn        json.Number `json:"expires_in"`
+		Error            string      `json:"error"`
+		ErrorDescription string      `json:"error_description"`
+	}
+	jsonErr := json.Unmarshal(body, &res)
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		if res.Error != "" {
+			return "", 0, fmt.Errorf("oauth2: token request failed with status %d: %s %s",
+				resp.StatusCode, res.Error, res.ErrorDescription)
+		}
+		return "", 0, fmt.Errorf("oauth2: token request failed with status %d", resp.StatusCode)
+	}
+
+	if jsonErr != nil {
+		return "", 0, fmt.Errorf("oauth2: unable to parse token response: %w", jsonErr)
+	}
+
+	if res.AccessToken == "" {
+		return "", 0, fmt.Errorf("oauth2: token response has no access_token")
+	}
+
+	if res.TokenType != "" && !strings.EqualFold(res.TokenType, "bearer") {
+		return "", 0, fmt.Errorf("oauth2: unsupported token type %q", res.TokenType)
+	}
+
+	expiresIn, _ := res.ExpiresIn.Int64()
+
+	return res.AccessToken, expiresIn, nil
+}
@@ -0,0 +1,160 @@
+package restclient
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTokenServer returns a token endpoint issuing "token-1", "token-2", ...
+// valid for expiresIn seconds.
+func newTokenServer(t *testing.T, expiresIn int, issued *int32) *httptest.Server {
+	t.Helper()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id, secret, ok := r.BasicAuth()
+		if !ok || id != "my-client" || secret != "s3cr3t" {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusUnauthorized)
+			fmt.Fprint(w, `{"error":"invalid_client","error_description":"bad credentials"}`)
+			return
+		}
+
+		require.NoError(t, r.ParseForm())
+		assert.Equal(t, "client_credentials", r.PostForm.Get("grant_type"))
+		assert.Equal(t, "read write", r.PostForm.Get("scope"))
+		assert.Equal(t, "https://api.example.com", r.PostForm.Get("audience"))
+
+		n := atomic.AddInt32(issued, 1)
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"access_token":"token-%d","token_type":"Bearer","expires_in":%d}`, n, expiresIn)
+	}))
+	t.Cleanup(srv.Close)
+
+	return srv
+}
+
+func newTestOAuth2RoundTripper(tokenURL, secret string) *oauth2RoundTripper {
+	return newOAuth2RoundTripper(&Config{
+		OAuthTokenURL:     tokenURL,
+		OAuthClientID:     "my-client",
+		OAuthClientSecret: secret,
+		OAuthScopes:       "read,write",
+		OAuthAudience:     "https://api.example.com",
+	}, http.DefaultTransport)
+}
+
+func TestOAuth2RoundTripper_CachesToken(t *testing.T) {
+	var issued int32
+	tokenSrv := newTokenServer(t, 3600, &issued)
+
+	var auth []string
+	apiSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth = append(auth, r.Header.Get("Authorization"))
+	}))
+	defer apiSrv.Close()
+
+	cli := &http.Client{Transport: newTestOAuth2RoundTripper(tokenSrv.URL, "s3cr3t")}
+	for range 3 {
+		resp, err := cli.Get(apiSrv.URL)
+		require.NoError(t, err)
+		resp.Body.Close()
+	}
+
+	assert.Equal(t, int32(1), atomic.LoadInt32(&issued))
+	assert.Equal(t, []string{"Bearer token-1", "Bearer token-1", "Bearer token-1"}, auth)
+}
+
+func TestOAuth2RoundTripper_RefreshesExpiredToken(t *testing.T) {
+	var issued int32
+	tokenSrv := newTokenServer(t, 60, &issued)
+
+	var auth []string
+	apiSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth = append(auth, r.Header.Get("Authorization"))
+	}))
+	defer apiSrv.Close()
+
+	now := time.Now()
+	rt := newTestOAuth2RoundTripper(tokenSrv.URL, "s3cr3t")
+	rt.now = func() time.Time { return now }
+
+	cli := &http.Client{Transport: rt}
+	for _, elapsed := range []time.Duration{0, 30 * time.Second, 55 * time.Second} {
+		now = now.Add(elapsed)
+		resp, err := cli.Get(apiSrv.URL)
+		require.NoError(t, err)
+		resp.Body.Close()
+	}
+
+	// the token is renewed expiryDelta before it expires
+	assert.Equal(t, []string{"Bearer token-1", "Bearer token-1", "Bearer token-2"}, auth)
+}
+
+func TestOAuth2RoundTripper_RetriesOnceOn401(t *testing.T) {
+	var issued int32
+	tokenSrv := newTokenServer(t, 3600, &issued)
+
+	var bodies []string
+	apiSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		bin, _ := io.ReadAll(r.Body)
+		bodies = append(bodies, string(bin))
+
+		// only the second token is accepted
+		if r.Header.Get("Authorization") != "Bearer token-2" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+	}))
+	defer apiSrv.Close()
+
+	cli := &http.Client{Transport: newTestOAuth2RoundTripper(tokenSrv.URL, "s3cr3t")}
+
+	resp, err := cli.Post(apiSrv.URL, "application/json", strings.NewReader(`{"a":1}`))
+	require.NoError(t, err)
+	resp.Body.Close()
+
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&issued))
+	assert.Equal(t, []string{`{"a":1}`, `{"a":1}`}, bodies)
+}
+
+func TestOAuth2RoundTripper_Unauthorized(t *testing.T) {
+	var issued int32
+	tokenSrv := newTokenServer(t, 3600, &issued)
+
+	hits := 0
+	apiSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits++
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer apiSrv.Close()
+
+	cli := &http.Client{Transport: newTestOAuth2RoundTripper(tokenSrv.URL, "s3cr3t")}
+
+	resp, err := cli.Get(apiSrv.URL)
+	require.NoError(t, err)
+	resp.Body.Close()
+
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+	assert.Equal(t, 2, hits)
+}
+
+func TestOAuth2RoundTripper_TokenError(t *testing.T) {
+	var issued int32
+	tokenSrv := newTokenServer(t, 3600, &issued)
+
+	cli := &http.Client{Transport: newTestOAuth2RoundTripper(tokenSrv.URL, "wrong")}
+
+	_, err := cli.Get("http://127.0.0.1:1")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "status 401: invalid_client bad credentials")
+}
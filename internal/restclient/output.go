@@ -0,0 +1,124 @@
+package restclient
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/lucasepe/resto/internal/util/jq"
+	yamlutil "github.com/lucasepe/resto/internal/util/yaml"
+)
+
+// OutputFormat is the rendering applied to the JSON responses.
+type OutputFormat string
+
+const (
+	// OutputRaw copies the response body byte-for-byte.
+	OutputRaw OutputFormat = "raw"
+	// OutputJSON renders JSON responses compacted on a single line.
+	OutputJSON OutputFormat = "json"
+	// OutputPrettyJSON renders JSON responses indented.
+	OutputPrettyJSON OutputFormat = "pretty-json"
+	// OutputYAML renders JSON responses as YAML.
+	OutputYAML OutputFormat = "yaml"
+)
+
+// ParseOutputFormat returns the OutputFormat for the given name;
+// an empty name means OutputRaw.
+func ParseOutputFormat(s string) (OutputFormat, error) {
+	switch res := OutputFormat(strings.ToLower(strings.TrimSpace(s))); res {
+	case "":
+		return OutputRaw, nil
+	case OutputRaw, OutputJSON, OutputPrettyJSON, OutputYAML:
+		return res, nil
+	default:
+		return "", fmt.Errorf("unsupported output format %q (use yaml, json, pretty-json or raw)", s)
+	}
+}
+
+// writeFormatted renders the JSON body in the given format, preserving
+// the key order. Bodies that are not valid JSON are written as is.
+func writeFormatted(wri io.Writer, body []byte, format OutputFormat) error {
+	if format == OutputRaw || format == "" || !json.Valid(body) {
+		_, err := wri.Write(body)
+		return err
+	}
+
+	var buf bytes.Buffer
+
+	switch format {
+	case OutputJSON:
+		if err := json.Compact(&buf, body); err != nil {
+			return err
+		}
+		buf.WriteByte('\n')
+
+	case OutputPrettyJSON:
+		if err := json.Indent(&buf, body, "", "  "); err != nil {
+			return err
+		}
+		buf.WriteByte('\n')
+
+	case OutputYAML:
+		res, err := yamlutil.FromJSON(body)
+		if err != nil {
+			return err
+		}
+		buf.Write(res)
+	}
+
+	_, err := buf.WriteTo(wri)
+	return err
+}
+
+// writeQueryResults applies the JQ query to the JSON body and writes
+// each result to wri, one per line, as compact JSON; with OutputPrettyJSON
+// results are indented and with OutputYAML they are written as YAML documents.
+//
+// If raw is true, string results are written as is, without quotes
+// (like jq --raw-output).
+func writeQueryResults(wri io.Writer, body []byte, query string, raw bool, format OutputFormat) error {
+	if len(bytes.TrimSpace(body)) == 0 {
+		return nil
+	}
+
+	res, err := jq.Eval(body, query)
+	if err != nil {
+		return err
+	}
+
+	// query results are re-encoded, there are no raw bytes to copy
+	if format == OutputRaw || format == "" {
+		format = OutputJSON
+	}
+
+	for i, el := range res {
+		if s, ok := el.(string); ok && raw {
+			if _, err := fmt.Fprintln(wri, s); err != nil {
+				return err
+			}
+			continue
+		}
+
+		var buf bytes.Buffer
+		enc := json.NewEncoder(&buf)
+		enc.SetEscapeHTML(false)
+		if err := enc.Encode(el); err != nil {
+			return err
+		}
+
+		if format == OutputYAML && i > 0 {
+			if _, err := fmt.Fprintln(wri, "---"); err != nil {
+				return err
+			}
+		}
+
+		if err := writeFormatted(wri, buf.Bytes(), format); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,125 @@
+package restclient
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestWriteQueryResults(t *testing.T) {
+	body := []byte(`{"items": [{"name": "a", "size": 1}, {"name": "b<c>", "size": 2}]}`)
+
+	tests := []struct {
+		name    string
+		query   string
+		raw     bool
+		want    string
+		wantErr bool
+	}{
+		{
+			name:  "json strings",
+			query: ".items[].name",
+			want:  "\"a\"\n\"b<c>\"\n",
+		},
+		{
+			name:  "raw strings",
+			query: ".items[].name",
+			raw:   true,
+			want:  "a\nb<c>\n",
+		},
+		{
+			name:  "raw does not affect objects",
+			query: ".items[0]",
+			raw:   true,
+			want:  "{\"name\":\"a\",\"size\":1}\n",
+		},
+		{
+			name:  "numbers",
+			query: "[.items[].size] | add",
+			want:  "3\n",
+		},
+		{
+			name:    "invalid expression",
+			query:   ".items[",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			err := writeQueryResults(&buf, body, tt.query, tt.raw, OutputRaw)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("unexpected error status: got err=%v, wantErr=%v", err, tt.wantErr)
+			}
+			if got := buf.String(); got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWriteFormatted(t *testing.T) {
+	body := []byte(`{"zeta": 1, "alpha": ["x"]}`)
+
+	tests := []struct {
+		name   string
+		body   []byte
+		format OutputFormat
+		want   string
+	}{
+		{
+			name:   "raw",
+			body:   body,
+			format: OutputRaw,
+			want:   `{"zeta": 1, "alpha": ["x"]}`,
+		},
+		{
+			name:   "json",
+			body:   body,
+			format: OutputJSON,
+			want:   "{\"zeta\":1,\"alpha\":[\"x\"]}\n",
+		},
+		{
+			name:   "pretty json",
+			body:   body,
+			format: OutputPrettyJSON,
+			want:   "{\n  \"zeta\": 1,\n  \"alpha\": [\n    \"x\"\n  ]\n}\n",
+		},
+		{
+			name:   "yaml",
+			body:   body,
+			format: OutputYAML,
+			want:   "zeta: 1\nalpha:\n  - x\n",
+		},
+		{
+			name:   "not json is written as is",
+			body:   []byte("plain text"),
+			format: OutputYAML,
+			want:   "plain text",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := writeFormatted(&buf, tt.body, tt.format); err != nil {
+				t.Fatal(err)
+			}
+			if got := buf.String(); got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseOutputFormat(t *testing.T) {
+	for _, el := range []string{"", "raw", "json", "Pretty-JSON", "yaml"} {
+		if _, err := ParseOutputFormat(el); err != nil {
+			t.Errorf("unexpected error for %q: %v", el, err)
+		}
+	}
+
+	if _, err := ParseOutputFormat("xml"); err == nil {
+		t.Error("expected error for unsupported format")
+	}
+}
@@ -0,0 +1,363 @@
+package restclient
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/lucasepe/resto/internal/util/jq"
+)
+
+// doPages requests the first page and then follows the next page links
+// until there are none left.
+//
+// The next page is found, in order of preference:
+//   - evaluating the nextExpr JQ expression on the JSON page: the result is
+//     a cursor token sent in the nextParam query parameter of the original
+//     URL or, if nextParam is empty, the URL of the next page
+//   - the RFC 8288 (formerly RFC 5988) Link header with rel="next"
+//
+// Each page is written as soon as it is received unless merge is set:
+// then the pages are merged into a single JSON document and written at
+// the end (see pageMerger).
+func (hc *restClientImpl) doPages(ctx context.Context, cli *http.Client, method, uri string, streams IOStreams) error {
+	// the same body (if any) is sent for every page
+	var payload []byte
+	if streams.In != nil {
+		var err error
+		if payload, err = io.ReadAll(streams.In); err != nil {
+			return err
+		}
+	}
+
+	out := streams.Out
+	if out == nil {
+		out = io.Discard
+	}
+
+	var (
+		merger pageMerger
+		seen   = map[string]bool{}
+		next   = uri
+	)
+
+	for page := 0; next != ""; page++ {
+		if seen[next] {
+			return fmt.Errorf("pagination loop detected: %s was already requested", next)
+		}
+		seen[next] = true
+
+		var body io.Reader
+		if payload != nil {
+			body = bytes.NewReader(payload)
+		}
+
+		call, err := http.NewRequestWithContext(ctx, method, next, body)
+		if err != nil {
+			return err
+		}
+		setHeaders(call, hc.requestHeaders...)
+
+		respo, err := cli.Do(call)
+		if err != nil {
+			return err
+		}
+
+		var buf bytes.Buffer
+		err = dumpResponse(respo, &buf, streams.Err)
+		respo.Body.Close()
+		if err != nil {
+			return err
+		}
+
+		current := next
+		next, err = hc.nextPage(uri, current, respo.Header, buf.Bytes())
+		if err != nil {
+			return err
+		}
+
+		if hc.merge {
+			if err := merger.add(buf.Bytes()); err != nil {
+				return fmt.Errorf("unable to merge page %d (%s): %w", page+1, current, err)
+			}
+			continue
+		}
+
+		if hc.output == OutputYAML && page > 0 {
+			if _, err := fmt.Fprintln(out, "---"); err != nil {
+				return err
+			}
+		}
+
+		if err := hc.writeBody(out, buf.Bytes(), hc.output); err != nil {
+			return err
+		}
+	}
+
+	if !hc.merge {
+		return nil
+	}
+
+	res, err := merger.bytes()
+	if err != nil {
+		return err
+	}
+
+	// the merged document is built here, there are no raw bytes to copy
+	format := hc.output
+	if format == OutputRaw || format == "" {
+		format = OutputJSON
+	}
+
+	return hc.writeBody(out, res, format)
+}
+
+// writeBody writes the response body applying the query and the output format.
+func (hc *restClientImpl) writeBody(out io.Writer, body []byte, format OutputFormat) error {
+	if hc.query != "" {
+		return writeQueryResults(out, body, hc.query, hc.rawOutput, format)
+	}
+
+	return writeFormatted(out, body, format)
+}
+
+// nextPage returns the URL of the page after current, or an empty string.
+func (hc *restClientImpl) nextPage(first, current string, header http.Header, body []byte) (string, error) {
+	if hc.nextExpr == "" {
+		next := linkNext(header.Values("Link"))
+		if next == "" {
+			return "", nil
+		}
+		return resolveURL(current, next)
+	}
+
+	res, err := jq.Eval(body, hc.nextExpr)
+	if err != nil {
+		return "", err
+	}
+
+	// null, false and empty strings mean there are no more pages
+	var token string
+	if len(res) > 0 {
+		switch val := res[0].(type) {
+		case nil, bool:
+		case string:
+			token = strings.TrimSpace(val)
+		default:
+			token = fmt.Sprint(val)
+		}
+	}
+	if token == "" {
+		return "", nil
+	}
+
+	if hc.nextParam == "" {
+		return resolveURL(current, token)
+	}
+
+	u, err := url.Parse(first)
+	if err != nil {
+		return "", err
+	}
+	qs := u.Query()
+	qs.Set(hc.nextParam, token)
+	u.RawQuery = qs.Encode()
+
+	return u.String(), nil
+}
+
+// linkNext returns the target of the rel="next" link of the given
+// Link header values, or an empty string.
+//
+// Example:
+//
+//	Link: <https://api.github.com/repos?page=2>; rel="next", <https://api.github.com/repos?page=5>; rel="last"
+func linkNext(values []string) string {
+	for _, val := range values {
+		for _, link := range strings.Split(val, ",") {
+			target, params, ok := strings.Cut(strings.TrimSpace(link), ";")
+			if !ok {
+				continue
+			}
+
+			target = strings.TrimSpace(target)
+			if !strings.HasPrefix(target, "<") || !strings.HasSuffix(target, ">") {
+				continue
+			}
+
+			for _, el := range strings.Split(params, ";") {
+				key, rel, _ := strings.Cut(strings.TrimSpace(el), "=")
+				if !strings.EqualFold(strings.TrimSpace(key), "rel") {
+					continue
+				}
+
+				for _, name := range strings.Fields(strings.Trim(strings.TrimSpace(rel), `"`)) {
+					if strings.EqualFold(name, "next") {
+						return target[1 : len(target)-1]
+					}
+				}
+			}
+		}
+	}
+
+	return ""
+}
+
+// resolveURL resolves the (possibly relative) reference against base.
+func resolveURL(base, ref string) (string, error) {
+	b, err := url.Parse(base)
+	if err != nil {
+		return "", err
+	}
+
+	r, err := url.Parse(ref)
+	if err != nil {
+		return "", fmt.Errorf("invalid next page url %q: %w", ref, err)
+	}
+
+	return b.ResolveReference(r).String(), nil
+}
+
+// pageMerger merges JSON pages into a single document: arrays are
+// concatenated; for objects, array fields are concatenated while any
+// other field takes the value of the last page (e.g. the Kubernetes
+// list metadata). A null field becomes a list when a later page holds
+// an array. The key order of the first page is preserved.
+type pageMerger struct {
+	pages  int
+	array  bool
+	items  []json.RawMessage
+	keys   []string
+	fields map[string]json.RawMessage
+	arrays map[string][]json.RawMessage
+}
+
+func (m *pageMerger) add(page []byte) error {
+	page = bytes.TrimSpace(page)
+	if len(page) == 0 {
+		return nil
+	}
+
+	if !json.Valid(page) {
+		return fmt.Errorf("page is not valid JSON")
+	}
+
+	isArray := page[0] == '['
+	if !isArray && page[0] != '{' {
+		return fmt.Errorf("page is neither a JSON array nor an object")
+	}
+
+	if m.pages > 0 && isArray != m.array {
+		return fmt.Errorf("pages mix JSON arrays and objects")
+	}
+	m.pages++
+	m.array = isArray
+
+	if isArray {
+		var items []json.RawMessage
+		if err := json.Unmarshal(page, &items); err != nil {
+			return err
+		}
+		m.items = append(m.items, items...)
+		return nil
+	}
+
+	if m.fields == nil {
+		m.fields = map[string]json.RawMessage{}
+		m.arrays = map[string][]json.RawMessage{}
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(page))
+	if _, err := dec.Token(); err != nil { // {
+		return err
+	}
+
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return err
+		}
+		key := tok.(string)
+
+		var val json.RawMessage
+		if err := dec.Decode(&val); err != nil {
+			return err
+		}
+
+		_, isField := m.fields[key]
+		_, isList := m.arrays[key]
+		if !isField && !isList {
+			m.keys = append(m.keys, key)
+		}
+
+		// a missing list on the last page must not drop the previous items
+		if isList && string(val) == "null" {
+			continue
+		}
+
+		// a null field is not yet known not to be a list (e.g. an
+		// empty first page), an array on a later page makes it one
+		if val[0] == '[' && (!isField || string(m.fields[key]) == "null") {
+			delete(m.fields, key)
+			var items []json.RawMessage
+			if err := json.Unmarshal(val, &items); err != nil {
+				return err
+			}
+			m.arrays[key] = append(m.arrays[key], items...)
+			continue
+		}
+
+		delete(m.arrays, key)
+		m.fields[key] = val
+	}
+
+	return nil
+}
+
+func (m *pageMerger) bytes() ([]byte, error) {
+	var buf bytes.Buffer
+
+	if m.array || m.pages == 0 {
+		writeArray(&buf, m.items)
+		return buf.Bytes(), nil
+	}
+
+	buf.WriteByte('{')
+	for i, key := range m.keys {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+
+		name, err := json.Marshal(key)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(name)
+		buf.WriteByte(':')
+
+		if val, ok := m.fields[key]; ok {
+			buf.Write(val)
+			continue
+		}
+		writeArray(&buf, m.arrays[key])
+	}
+	buf.WriteByte('}')
+
+	return buf.Bytes(), nil
+}
+
+func writeArray(buf *bytes.Buffer, items []json.RawMessage) {
+	buf.WriteByte('[')
+	for i, el := range items {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		buf.Write(el)
+	}
+	buf.WriteByte(']')
+}
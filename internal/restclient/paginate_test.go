@@ -0,0 +1,179 @@
+package restclient
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+)
+
+func TestLinkNext(t *testing.T) {
+	tests := []struct {
+		values []string
+		want   string
+	}{
+		{nil, ""},
+		{[]string{`<https://api.github.com/repos?page=2>; rel="next", <https://api.github.com/repos?page=5>; rel="last"`}, "https://api.github.com/repos?page=2"},
+		{[]string{`<https://api.github.com/repos?page=1>; rel="prev"`, `</repos?page=3>; rel=next`}, "/repos?page=3"},
+		{[]string{`</items?cursor=abc>; title="more"; rel="next last"`}, "/items?cursor=abc"},
+		{[]string{`</items?page=1>; rel="first"`}, ""},
+	}
+
+	for i, tc := range tests {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			if got := linkNext(tc.values); got != tc.want {
+				t.Errorf("got %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestPageMerger(t *testing.T) {
+	tests := []struct {
+		name  string
+		pages []string
+		want  string
+	}{
+		{
+			name:  "arrays",
+			pages: []string{`[1, 2]`, `[]`, `[3]`},
+			want:  `[1,2,3]`,
+		},
+		{
+			name: "kubernetes list",
+			pages: []string{
+				`{"kind": "PodList", "metadata": {"continue": "a"}, "items": [{"n": 1}]}`,
+				`{"kind": "PodList", "metadata": {}, "items": [{"n": 2}]}`,
+			},
+			want: `{"kind":"PodList","metadata":{},"items":[{"n": 1},{"n": 2}]}`,
+		},
+		{
+			name:  "missing list on the last page",
+			pages: []string{`{"data": [1], "next": "x"}`, `{"data": null, "next": null}`},
+			want:  `{"data":[1],"next":null}`,
+		},
+		{
+			name: "missing list on the first page",
+			pages: []string{
+				`{"data": null, "next": "x"}`,
+				`{"data": [1, 2], "next": "y"}`,
+				`{"data": null, "next": "z"}`,
+				`{"data": [3], "next": null}`,
+			},
+			want: `{"data":[1,2,3],"next":null}`,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var m pageMerger
+			for _, el := range tc.pages {
+				if err := m.add([]byte(el)); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			got, err := m.bytes()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != tc.want {
+				t.Errorf("got %s, want %s", got, tc.want)
+			}
+		})
+	}
+
+	t.Run("mixed pages", func(t *testing.T) {
+		var m pageMerger
+		m.add([]byte(`[1]`))
+		if err := m.add([]byte(`{"items": [2]}`)); err == nil {
+			t.Fatal("expected error")
+		}
+	})
+}
+
+func TestRESTClient_DoPaginate(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/link":
+			page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+			if page < 3 {
+				w.Header().Set("Link", fmt.Sprintf(`</link?page=%d>; rel="next"`, page+1))
+			}
+			fmt.Fprintf(w, `[{"page": %d}]`, page)
+
+		case "/pods":
+			w.Header().Set("Content-Type", "application/json")
+			switch r.URL.Query().Get("continue") {
+			case "":
+				fmt.Fprint(w, `{"metadata": {"continue": "t1"}, "items": [{"name": "a"}]}`)
+			case "t1":
+				fmt.Fprint(w, `{"metadata": {"continue": "t2"}, "items": [{"name": "b"}]}`)
+			default:
+				fmt.Fprint(w, `{"metadata": {}, "items": [{"name": "c"}]}`)
+			}
+
+		case "/cursor":
+			if r.URL.Query().Get("after") == "" {
+				fmt.Fprint(w, `{"data": ["x"], "next": "/cursor?after=x&limit=1"}`)
+				return
+			}
+			fmt.Fprint(w, `{"data": ["y"], "next": null}`)
+		}
+	}))
+	defer ts.Close()
+
+	tests := []struct {
+		name string
+		opts RequestOptions
+		want string
+	}{
+		{
+			name: "link header",
+			opts: RequestOptions{Path: "/link", Params: []string{"page:1"}, Paginate: true},
+			want: `[{"page": 1}][{"page": 2}][{"page": 3}]`,
+		},
+		{
+			name: "link header merged",
+			opts: RequestOptions{Path: "/link", Params: []string{"page:1"}, Paginate: true, Merge: true},
+			want: `[{"page":1},{"page":2},{"page":3}]` + "\n",
+		},
+		{
+			name: "kubernetes continue token",
+			opts: RequestOptions{
+				Path:      "/pods",
+				Params:    []string{"limit:1"},
+				Paginate:  true,
+				NextExpr:  ".metadata.continue",
+				NextParam: "continue",
+				Merge:     true,
+				Query:     ".items[].name",
+				RawOutput: true,
+			},
+			want: "a\nb\nc\n",
+		},
+		{
+			name: "next url",
+			opts: RequestOptions{Path: "/cursor", Paginate: true, NextExpr: ".next", Query: ".data[]"},
+			want: "\"x\"\n\"y\"\n",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var out bytes.Buffer
+			tc.opts.BaseURL = ts.URL
+
+			err := New(tc.opts).Do(context.Background(), ts.Client(), IOStreams{Out: &out})
+			if err != nil {
+				t.Fatalf("Do() error = %v", err)
+			}
+			if got := out.String(); got != tc.want {
+				t.Errorf("got %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
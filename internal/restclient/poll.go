@@ -0,0 +1,56 @@
+package restclient
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/lucasepe/resto/internal/util/retry"
+)
+
+// isPolling reports whether the request must be sent once, and the
+// until condition then polled with GET requests: retrying a request
+// that is not safe would repeat its side effects. Streams are never
+// polled, the condition is evaluated on their events.
+func (hc *restClientImpl) isPolling(method string) bool {
+	return hc.until != "" && !hc.stream && method != http.MethodGet && method != http.MethodHead
+}
+
+// pollRequest sends the request once (retrying it only on the transport
+// failures) and returns the GET request that polls its outcome, as in
+// the "202 Accepted" pattern.
+//
+// The polled URL is, in order of preference, the pollURL option, the
+// Location header of the response and the request URL itself.
+func (hc *restClientImpl) pollRequest(ctx context.Context, cli *http.Client, call *http.Request, streams IOStreams) (*http.Request, error) {
+	respo, err := cli.Do(call.WithContext(retry.WithoutCondition(ctx)))
+	if err != nil {
+		return nil, err
+	}
+
+	// only the outcome is printed, the response is shown on failure
+	if err := dumpResponse(respo, io.Discard, streams.Err); err != nil {
+		return nil, err
+	}
+
+	target := call.URL.String()
+	switch {
+	case hc.pollURL != "":
+		target, err = resolveURL(target, hc.pollURL)
+	case respo.Header.Get("Location") != "":
+		target, err = resolveURL(target, respo.Header.Get("Location"))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("unable to poll: %w", err)
+	}
+
+	res, err := http.NewRequestWithContext(ctx, http.MethodGet, target, nil)
+	if err != nil {
+		return nil, err
+	}
+	setHeaders(res, hc.requestHeaders...)
+	res.Header.Del("Content-Type")
+
+	return res, nil
+}
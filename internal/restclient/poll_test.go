@@ -0,0 +1,191 @@
+package restclient
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/lucasepe/resto/internal/util/retry"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRESTClient_DoPolls(t *testing.T) {
+	tests := []struct {
+		name      string
+		pollURL   string
+		location  string
+		postFails int
+		want      []string
+	}{
+		{
+			name:     "location header",
+			location: "/jobs/42",
+			want:     []string{"POST /jobs", "GET /jobs/42", "GET /jobs/42", "GET /jobs/42"},
+		},
+		{
+			name:     "poll url wins over location",
+			pollURL:  "status/42",
+			location: "/jobs/42",
+			want:     []string{"POST /jobs", "GET /status/42", "GET /status/42", "GET /status/42"},
+		},
+		{
+			name: "request url",
+			want: []string{"POST /jobs", "GET /jobs", "GET /jobs", "GET /jobs"},
+		},
+		{
+			name:      "request retried on failures only",
+			location:  "/jobs/42",
+			postFails: 1,
+			want:      []string{"POST /jobs", "POST /jobs", "GET /jobs/42", "GET /jobs/42", "GET /jobs/42"},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var (
+				mu    sync.Mutex
+				calls []string
+				polls int
+			)
+
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				mu.Lock()
+				defer mu.Unlock()
+				calls = append(calls, r.Method+" "+r.URL.Path)
+
+				w.Header().Set("Content-Type", "application/json")
+				if r.Method == http.MethodPost {
+					assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+					if tc.postFails > 0 {
+						tc.postFails--
+						w.WriteHeader(http.StatusServiceUnavailable)
+						return
+					}
+					if tc.location != "" {
+						w.Header().Set("Location", tc.location)
+					}
+					w.WriteHeader(http.StatusAccepted)
+					io.WriteString(w, `{"status":"pending"}`)
+					return
+				}
+
+				assert.Empty(t, r.Header.Get("Content-Type"))
+				polls++
+				status := "pending"
+				if polls > 2 {
+					status = "done"
+				}
+				io.WriteString(w, `{"status":"`+status+`"}`)
+			}))
+			defer srv.Close()
+
+			retryOn, err := retry.ParseRetryOn("5xx")
+			require.NoError(t, err)
+
+			cli := &http.Client{
+				Transport: retry.NewRoundTripper(http.DefaultTransport, retry.RoundTripperOptions{
+					Until:    `.status == "done"`,
+					RetryOn:  retryOn,
+					Strategy: retry.Jittered(0),
+					Retrier: retry.NewRetrier(retry.RetryOptions{
+						InitialDelay: time.Millisecond,
+						MaxDelay:     time.Millisecond,
+						MaxAttempts:  5,
+					}),
+				}),
+			}
+
+			var out bytes.Buffer
+			err = New(RequestOptions{
+				BaseURL: srv.URL,
+				Path:    "/jobs",
+				Method:  http.MethodPost,
+				Headers: []string{"Content-Type: application/json"},
+				Until:   `.status == "done"`,
+				PollURL: tc.pollURL,
+			}).Do(context.Background(), cli, IOStreams{
+				In:  strings.NewReader(`{"task":"build"}`),
+				Out: &out,
+				Err: io.Discard,
+			})
+			require.NoError(t, err)
+
+			assert.Equal(t, tc.want, calls)
+			assert.Equal(t, `{"status":"done"}`, out.String())
+		})
+	}
+}
+
+func TestRESTClient_DoPollsFailedRequest(t *testing.T) {
+	calls := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.WriteHeader(http.StatusConflict)
+		io.WriteString(w, `{"message":"already exists"}`)
+	}))
+	defer srv.Close()
+
+	var errBuf bytes.Buffer
+	err := New(RequestOptions{
+		BaseURL: srv.URL,
+		Method:  http.MethodPost,
+		Until:   `.status == "done"`,
+	}).Do(context.Background(), srv.Client(), IOStreams{Out: io.Discard, Err: &errBuf})
+
+	var httpErr *HTTPError
+	require.ErrorAs(t, err, &httpErr)
+	assert.Equal(t, http.StatusConflict, httpErr.StatusCode)
+	assert.Equal(t, 1, calls)
+	assert.Equal(t, `{"message":"already exists"}`, errBuf.String())
+}
+
+func TestRESTClient_DoStreamIsNotPolled(t *testing.T) {
+	var (
+		mu    sync.Mutex
+		calls []string
+	)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		calls = append(calls, r.Method+" "+r.URL.Path)
+		mu.Unlock()
+
+		w.Header().Set("Content-Type", "application/json")
+		io.WriteString(w, `{"stream": "Step 1/2"}`+"\n")
+		w.(http.Flusher).Flush()
+		io.WriteString(w, `{"aux": {"ID": "sha256:42"}}`+"\n")
+		w.(http.Flusher).Flush()
+		<-r.Context().Done()
+	}))
+	defer srv.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var out bytes.Buffer
+	err := New(RequestOptions{
+		BaseURL: srv.URL,
+		Path:    "/build",
+		Method:  http.MethodPost,
+		Stream:  true,
+		Until:   `.aux.ID != null`,
+		Query:   ".stream // .aux.ID",
+	}).Do(ctx, srv.Client(), IOStreams{
+		In:  strings.NewReader("context"),
+		Out: &out,
+		Err: io.Discard,
+	})
+	require.NoError(t, err)
+
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Equal(t, []string{"POST /build"}, calls)
+	assert.Equal(t, "\"Step 1/2\"\n\"sha256:42\"\n", out.String())
+}
@@ -8,10 +8,19 @@ import (
 	"strings"
 )
 
+// HTTPError is returned when the server answers with a non 2xx status code.
+type HTTPError struct {
+	StatusCode int
+}
+
+func (e *HTTPError) Error() string {
+	return fmt.Sprintf("http request failed with status: %d %s", e.StatusCode, http.StatusText(e.StatusCode))
+}
+
 // dumpResponse copies the HTTP response body to the appropriate writer based on
 // the response status code. If the status code indicates success (2xx), the body
 // is copied to okWri. Otherwise, it is copied to koWri and an error is returned
-// indicating the failure status.
+// of type *HTTPError indicating the failure status.
 //
 // If copying the body fails, the function returns an error wrapping the cause.
 //
@@ -32,7 +41,7 @@ func dumpResponse(res *http.Response, outwri, errwri io.Writer) error {
 	statusOK := res.StatusCode >= 200 && res.StatusCode < 300
 	if res.Body == nil {
 		if !statusOK {
-			return fmt.Errorf("http request failed with status: %d %s", res.StatusCode, http.StatusText(res.StatusCode))
+			return &HTTPError{StatusCode: res.StatusCode}
 		}
 		return nil
 	}
@@ -41,10 +50,10 @@ func dumpResponse(res *http.Response, outwri, errwri io.Writer) error {
 	if !statusOK {
 		_, err := io.Copy(errwri, res.Body)
 		if err != nil {
-			return fmt.Errorf("http status %d; also failed to read body: %w", res.StatusCode, err)
+			return fmt.Errorf("%w; also failed to read body: %w", &HTTPError{StatusCode: res.StatusCode}, err)
 		}
 
-		return fmt.Errorf("http request failed with status: %d %s", res.StatusCode, http.StatusText(res.StatusCode))
+		return &HTTPError{StatusCode: res.StatusCode}
 	}
 
 	_, err := io.Copy(outwri, res.Body)
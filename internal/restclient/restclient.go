@@ -1,9 +1,13 @@
 package restclient
 
 import (
+	"bytes"
 	"context"
 	"net/http"
 	"strings"
+
+	ioutil "github.com/lucasepe/resto/internal/util/io"
+	"github.com/lucasepe/resto/internal/util/retry"
 )
 
 type RESTClient interface {
@@ -17,13 +21,70 @@ type RequestOptions struct {
 	Params  []string
 	Headers []string
 	Streams IOStreams
+	// Query is an optional JQ expression applied to the JSON
+	// response body; its results are printed instead of the body.
+	Query string
+	// RawOutput prints string query results without quotes.
+	RawOutput bool
+	// Output is the rendering of JSON responses (default: OutputRaw).
+	Output OutputFormat
+	// Stream reads the response as a sequence of JSON events, printing
+	// each one as soon as it arrives. Responses with a streaming content
+	// type (e.g. application/x-ndjson) are always read this way.
+	Stream bool
+	// Until is the JQ expression evaluated on every streamed event (on the
+	// data of server-sent events); the stream is closed at the first match.
+	Until string
+	// FailIf is the JQ expression evaluated on every streamed event;
+	// the first event that satisfies it fails the request.
+	FailIf string
+	// Reconnect configures the resumption of server-sent events
+	// streams (text/event-stream) when the connection drops.
+	Reconnect ReconnectOptions
+	// Paginate follows the next page links (see NextExpr) and
+	// writes every page.
+	Paginate bool
+	// NextExpr is the JQ expression that extracts the next page URL (or
+	// cursor, see NextParam) from the JSON page; when empty the
+	// Link header with rel="next" is followed.
+	NextExpr string
+	// NextParam is the query parameter the cursor extracted by
+	// NextExpr is sent in (e.g. "continue" for Kubernetes).
+	NextParam string
+	// Merge writes a single JSON document concatenating the
+	// item arrays of all the pages instead of every page.
+	Merge bool
+	// PollURL is where the Until condition of a request that is not a
+	// GET (e.g. a POST answered with 202 Accepted) is polled; when empty
+	// the Location header of the response, or the request URL, is used.
+	PollURL string
+	// Async follows the long-running operation a 202 Accepted response
+	// points to (Azure-AsyncOperation, Operation-Location or Location
+	// header) and writes the resulting resource.
+	Async bool
+	// Operation configures the polling of the Async operation.
+	Operation OperationOptions
 }
 
 func New(opts RequestOptions) RESTClient {
 	rc := &restClientImpl{
-		baseURL: opts.BaseURL,
-		urlPath: opts.Path,
-		verb:    opts.Method,
+		baseURL:   opts.BaseURL,
+		urlPath:   opts.Path,
+		verb:      opts.Method,
+		query:     opts.Query,
+		rawOutput: opts.RawOutput,
+		output:    opts.Output,
+		stream:    opts.Stream,
+		until:     opts.Until,
+		failIf:    opts.FailIf,
+		reconnect: opts.Reconnect,
+		paginate:  opts.Paginate,
+		nextExpr:  opts.NextExpr,
+		nextParam: opts.NextParam,
+		merge:     opts.Merge,
+		pollURL:   opts.PollURL,
+		async:     opts.Async,
+		operation: opts.Operation,
 	}
 
 	if tot := len(opts.Headers); tot > 0 {
@@ -40,6 +101,22 @@ func New(opts RequestOptions) RESTClient {
 		rc.verb = http.MethodGet
 	}
 
+	if rc.operation.Retrier == nil {
+		rc.operation.Retrier = retry.NewRetrier(retry.OptionsFromEnv())
+	}
+
+	if rc.operation.Strategy == nil {
+		rc.operation.Strategy = retry.Exp()
+	}
+
+	if rc.operation.Clock == nil {
+		rc.operation.Clock = retry.RealClock()
+	}
+
+	if rc.reconnect.Clock == nil {
+		rc.reconnect.Clock = retry.RealClock()
+	}
+
 	return rc
 }
 
@@ -51,6 +128,20 @@ type restClientImpl struct {
 	verb           string
 	requestParams  []string
 	requestHeaders []string
+	query          string
+	rawOutput      bool
+	output         OutputFormat
+	stream         bool
+	until          string
+	failIf         string
+	reconnect      ReconnectOptions
+	paginate       bool
+	nextExpr       string
+	nextParam      string
+	merge          bool
+	pollURL        string
+	async          bool
+	operation      OperationOptions
 }
 
 func (hc *restClientImpl) Do(ctx context.Context, cli *http.Client, streams IOStreams) error {
@@ -64,18 +155,72 @@ func (hc *restClientImpl) Do(ctx context.Context, cli *http.Client, streams IOSt
 		method = http.MethodGet
 	}
 
-	call, err := http.NewRequestWithContext(ctx, method, uri, streams.In)
+	if hc.paginate {
+		return hc.doPages(ctx, cli, method, uri, streams)
+	}
+
+	call, err := http.NewRequestWithContext(ctx, method, uri, nil)
 	if err != nil {
 		return err
 	}
 
+	// the body is kept, so that the retries can send it again
+	if streams.In != nil {
+		spool, err := newBodySpool(streams.In, maxMemoryBody)
+		if err != nil {
+			return err
+		}
+		defer spool.Close()
+
+		if err := setBody(call, spool); err != nil {
+			return err
+		}
+	}
+
 	setHeaders(call, hc.requestHeaders...)
 
+	if hc.async {
+		return hc.doAsync(ctx, cli, call, streams)
+	}
+
+	if hc.isPolling(method) {
+		if call, err = hc.pollRequest(ctx, cli, call, streams); err != nil {
+			return err
+		}
+	}
+
 	respo, err := cli.Do(call)
 	if err != nil {
 		return err
 	}
 	defer respo.Body.Close()
 
-	return dumpResponse(respo, streams.Out, streams.Err)
+	return hc.writeResponse(ctx, cli, call, respo, streams)
+}
+
+// writeResponse writes the response body as requested: as a stream
+// of events, as is or formatted and filtered by the query.
+func (hc *restClientImpl) writeResponse(ctx context.Context, cli *http.Client, call *http.Request, respo *http.Response, streams IOStreams) error {
+	if isEventStream(respo) {
+		return hc.writeEvents(ctx, cli, call, respo, streams)
+	}
+
+	if hc.stream || ioutil.IsStreamingContentType(respo.Header.Get("Content-Type")) {
+		return hc.writeStream(respo, streams)
+	}
+
+	if hc.query == "" && (hc.output == "" || hc.output == OutputRaw) {
+		return dumpResponse(respo, streams.Out, streams.Err)
+	}
+
+	var buf bytes.Buffer
+	if err := dumpResponse(respo, &buf, streams.Err); err != nil {
+		return err
+	}
+
+	if hc.query != "" {
+		return writeQueryResults(streams.Out, buf.Bytes(), hc.query, hc.rawOutput, hc.output)
+	}
+
+	return writeFormatted(streams.Out, buf.Bytes(), hc.output)
 }
@@ -0,0 +1,265 @@
+package restclient
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lucasepe/resto/internal/util/jq"
+	"github.com/lucasepe/resto/internal/util/retry"
+)
+
+// ReconnectOptions configures how a text/event-stream response
+// is resumed when the connection drops.
+type ReconnectOptions struct {
+	// MaxAttempts is the maximum number of reconnections; zero disables them.
+	MaxAttempts int
+	// Delay is the wait before reconnecting until the server
+	// sets its own with the retry field.
+	Delay time.Duration
+	// MaxDelay caps the wait requested by the server; zero means no cap.
+	MaxDelay time.Duration
+	// Clock waits before reconnecting; retry.RealClock when nil.
+	Clock retry.Clock
+}
+
+// sseEvent is a server-sent event, printed as a JSON line.
+type sseEvent struct {
+	ID    string `json:"id,omitempty"`
+	Event string `json:"event"`
+	// Data is the event payload: as is when it is valid JSON,
+	// a JSON string otherwise.
+	Data json.RawMessage `json:"data"`
+	// Retry is the reconnection time in milliseconds set by this event.
+	Retry int `json:"retry,omitempty"`
+}
+
+// isEventStream reports whether the response is a server-sent events stream.
+func isEventStream(res *http.Response) bool {
+	media, _, err := mime.ParseMediaType(res.Header.Get("Content-Type"))
+	return err == nil && strings.EqualFold(media, "text/event-stream")
+}
+
+// writeEvents prints the server-sent events as JSON lines as soon as they
+// arrive, evaluating until and failIf on the data of every event.
+//
+// When the connection drops before until is satisfied, the request is sent
+// again with the Last-Event-ID header, after the delay set by the server
+// with the retry field (capped by MaxDelay), at most MaxAttempts times;
+// a reconnection that fails to connect counts as an attempt.
+// A clean end of the stream is final when there is no until expression.
+func (hc *restClientImpl) writeEvents(ctx context.Context, cli *http.Client, req *http.Request, res *http.Response, streams IOStreams) error {
+	out := streams.Out
+	if out == nil {
+		out = io.Discard
+	}
+
+	state := sseState{delay: hc.reconnect.Delay}
+
+	var readErr error
+	for attempt := 0; ; attempt++ {
+		// res is nil when the last reconnection failed
+		if res != nil {
+			// 204 No Content tells the client to stop reconnecting
+			if res.StatusCode == http.StatusNoContent {
+				res.Body.Close()
+				break
+			}
+
+			if res.StatusCode < 200 || res.StatusCode > 299 {
+				return dumpResponse(res, streams.Out, streams.Err)
+			}
+
+			done, rerr, err := hc.readEvents(res.Body, out, &state)
+			res.Body.Close()
+			if err != nil || done {
+				return err
+			}
+
+			if rerr == nil && hc.until == "" {
+				return nil
+			}
+			readErr = rerr
+		}
+
+		if attempt >= hc.reconnect.MaxAttempts {
+			if readErr != nil && hc.until == "" {
+				return readErr
+			}
+			break
+		}
+
+		delay := state.delay
+		if maxDelay := hc.reconnect.MaxDelay; maxDelay > 0 && delay > maxDelay {
+			delay = maxDelay
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-hc.reconnect.Clock.After(delay):
+		}
+
+		var err error
+		next := req.Clone(ctx)
+		next.Body = http.NoBody
+		if req.GetBody != nil {
+			if next.Body, err = req.GetBody(); err != nil {
+				return err
+			}
+		}
+		if state.lastID != "" {
+			next.Header.Set("Last-Event-ID", state.lastID)
+		}
+
+		res, err = cli.Do(next)
+		if err != nil {
+			if ctx.Err() != nil {
+				return err
+			}
+			// a failed reconnection counts as an attempt
+			res, readErr = nil, err
+		}
+	}
+
+	if hc.until != "" {
+		return ErrStreamEnded
+	}
+
+	return nil
+}
+
+// sseState is what survives a reconnection.
+type sseState struct {
+	lastID string
+	delay  time.Duration
+	count  int
+}
+
+// readEvents parses the event stream, as specified by the HTML living
+// standard, dispatching each event when a blank line is found.
+//
+// It returns done when an event satisfies until, readErr when the
+// connection dropped and err for anything that must stop the stream.
+func (hc *restClientImpl) readEvents(body io.Reader, out io.Writer, state *sseState) (done bool, readErr, err error) {
+	var (
+		ev   sseEvent
+		data strings.Builder
+	)
+
+	// the id buffer outlives the single event
+	idBuf := state.lastID
+
+	br := bufio.NewReader(body)
+	for {
+		line, rerr := br.ReadString('\n')
+		if rerr != nil {
+			// an incomplete event is discarded
+			if rerr == io.EOF {
+				return false, nil, nil
+			}
+			if errors.Is(rerr, context.Canceled) || errors.Is(rerr, context.DeadlineExceeded) {
+				return false, nil, rerr
+			}
+			return false, rerr, nil
+		}
+		line = strings.TrimSuffix(strings.TrimSuffix(line, "\n"), "\r")
+
+		if line == "" {
+			state.lastID = idBuf
+			// an event without data fields is not dispatched
+			if data.Len() > 0 {
+				ev.ID = idBuf
+				done, err = hc.dispatchEvent(out, ev, strings.TrimSuffix(data.String(), "\n"), state)
+				if err != nil || done {
+					return done, nil, err
+				}
+			}
+			ev = sseEvent{}
+			data.Reset()
+			continue
+		}
+
+		field, value, _ := strings.Cut(line, ":")
+		value = strings.TrimPrefix(value, " ")
+
+		switch field {
+		case "": // comment
+		case "event":
+			ev.Event = value
+		case "data":
+			// even an empty data field makes the event dispatchable
+			data.WriteString(value)
+			data.WriteByte('\n')
+		case "id":
+			if !strings.ContainsRune(value, 0) {
+				idBuf = value
+			}
+		case "retry":
+			if isDigits(value) {
+				ms, _ := strconv.Atoi(value)
+				ev.Retry = ms
+				state.delay = time.Duration(ms) * time.Millisecond
+			}
+		}
+	}
+}
+
+func (hc *restClientImpl) dispatchEvent(out io.Writer, ev sseEvent, data string, state *sseState) (bool, error) {
+	if ev.Event == "" {
+		ev.Event = "message"
+	}
+
+	ev.Data = json.RawMessage(data)
+	if !json.Valid(ev.Data) {
+		ev.Data, _ = json.Marshal(data)
+	}
+
+	bin, err := json.Marshal(ev)
+	if err != nil {
+		return false, err
+	}
+
+	if hc.output == OutputYAML && state.count > 0 {
+		if _, err := io.WriteString(out, "---\n"); err != nil {
+			return false, err
+		}
+	}
+	state.count++
+
+	if err := hc.writeEvent(out, bin); err != nil {
+		return false, err
+	}
+
+	if hc.failIf != "" {
+		failed, err := jq.EvalBoolExpr(ev.Data, hc.failIf)
+		if err != nil {
+			return false, err
+		}
+		if failed {
+			return false, &retry.FailError{Expr: hc.failIf, Body: ev.Data}
+		}
+	}
+
+	if hc.until == "" {
+		return false, nil
+	}
+
+	return jq.EvalBoolExpr(ev.Data, hc.until)
+}
+
+func isDigits(s string) bool {
+	for _, c := range s {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return s != ""
+}
@@ -0,0 +1,178 @@
+package restclient
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/lucasepe/resto/internal/util/retry"
+)
+
+func TestRESTClient_DoEventStream(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprint(w, ": keep-alive comment\n\n")
+		fmt.Fprint(w, "event: progress\nid: 1\ndata: {\"done\": false,\ndata:  \"pct\": 50}\n\n")
+		fmt.Fprint(w, "retry: 1500\ndata: plain text\r\n\r\n")
+		fmt.Fprint(w, "data:\n\n")
+		fmt.Fprint(w, "data:\ndata: x\n\n")
+		fmt.Fprint(w, "event: progress\nid: 2\ndata: {\"done\": true}\n\n")
+		fmt.Fprint(w, "data: never printed\n\n")
+		w.(http.Flusher).Flush()
+		<-r.Context().Done()
+	}))
+	defer ts.Close()
+
+	var out bytes.Buffer
+	err := New(RequestOptions{BaseURL: ts.URL, Until: ".done? == true"}).
+		Do(context.Background(), ts.Client(), IOStreams{Out: &out})
+	if err != nil {
+		t.Fatalf("Do() error = %v", err)
+	}
+
+	want := `{"id":"1","event":"progress","data":{"done":false,"pct":50}}` + "\n" +
+		`{"id":"1","event":"message","data":"plain text","retry":1500}` + "\n" +
+		`{"id":"1","event":"message","data":""}` + "\n" +
+		`{"id":"1","event":"message","data":"\nx"}` + "\n" +
+		`{"id":"2","event":"progress","data":{"done":true}}` + "\n"
+	if got := out.String(); got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestRESTClient_DoEventStreamReconnect(t *testing.T) {
+	var (
+		mu      sync.Mutex
+		lastIDs []string
+	)
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		lastIDs = append(lastIDs, r.Header.Get("Last-Event-ID"))
+		n := len(lastIDs)
+		mu.Unlock()
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		// the connection drops after every event
+		fmt.Fprintf(w, "retry: 10\nid: %d\ndata: {\"n\": %d}\n\n", n, n)
+	}))
+	defer ts.Close()
+
+	clock := retry.NewFakeClock(time.Unix(0, 0))
+	opts := RequestOptions{
+		BaseURL:   ts.URL,
+		Query:     ".data.n",
+		Until:     ".n == 3",
+		Reconnect: ReconnectOptions{MaxAttempts: 5, Delay: time.Minute, Clock: clock},
+	}
+
+	var (
+		out bytes.Buffer
+		err error
+	)
+	clock.Run(func() {
+		err = New(opts).Do(context.Background(), ts.Client(), IOStreams{Out: &out})
+	})
+	if err != nil {
+		t.Fatalf("Do() error = %v", err)
+	}
+
+	// the server retry field replaces the initial delay
+	if got, want := fmt.Sprint(clock.Waits()), "[10ms 10ms]"; got != want {
+		t.Errorf("reconnection delays: got %s, want %s", got, want)
+	}
+
+	if got, want := out.String(), "1\n2\n3\n"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	if got, want := fmt.Sprint(lastIDs), "[ 1 2]"; got != want {
+		t.Errorf("Last-Event-ID headers: got %s, want %s", got, want)
+	}
+
+	t.Run("reconnections exhausted", func(t *testing.T) {
+		opts.Until = ".n == 100"
+		opts.Reconnect.MaxAttempts = 2
+
+		var err error
+		clock.Run(func() {
+			err = New(opts).Do(context.Background(), ts.Client(), IOStreams{Out: &out})
+		})
+		if !errors.Is(err, ErrStreamEnded) {
+			t.Fatalf("Do() error = %v, want %v", err, ErrStreamEnded)
+		}
+	})
+}
+
+func TestRESTClient_DoEventStreamReconnectFailure(t *testing.T) {
+	var (
+		mu      sync.Mutex
+		calls   int
+		lastIDs []string
+	)
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		calls++
+		n := calls
+		mu.Unlock()
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprintf(w, "retry: 10\nid: %d\ndata: {\"n\": %d}\n\n", n, n)
+	}))
+	defer ts.Close()
+
+	// the first reconnection fails before reaching the server
+	attempts := 0
+	cli := &http.Client{Transport: roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		attempts++
+		lastIDs = append(lastIDs, req.Header.Get("Last-Event-ID"))
+		if attempts == 2 {
+			return nil, errors.New("connection refused")
+		}
+		return ts.Client().Transport.RoundTrip(req)
+	})}
+
+	clock := retry.NewFakeClock(time.Unix(0, 0))
+	opts := RequestOptions{
+		BaseURL:   ts.URL,
+		Query:     ".data.n",
+		Until:     ".n == 2",
+		Reconnect: ReconnectOptions{MaxAttempts: 2, Delay: time.Minute, Clock: clock},
+	}
+
+	var (
+		out bytes.Buffer
+		err error
+	)
+	clock.Run(func() {
+		err = New(opts).Do(context.Background(), cli, IOStreams{Out: &out})
+	})
+	if err != nil {
+		t.Fatalf("Do() error = %v", err)
+	}
+
+	if got, want := fmt.Sprint(clock.Waits()), "[10ms 10ms]"; got != want {
+		t.Errorf("reconnection delays: got %s, want %s", got, want)
+	}
+
+	if got, want := out.String(), "1\n2\n"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	if got, want := fmt.Sprint(lastIDs), "[ 1 1]"; got != want {
+		t.Errorf("Last-Event-ID headers: got %s, want %s", got, want)
+	}
+}
+
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
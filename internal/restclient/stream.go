@@ -0,0 +1,120 @@
+package restclient
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/lucasepe/resto/internal/util/jq"
+	"github.com/lucasepe/resto/internal/util/retry"
+)
+
+// ErrStreamEnded is returned when a stream ends before any
+// of its events satisfied the until expression.
+var ErrStreamEnded = errors.New("stream ended before the until condition was met")
+
+// recordSeparator prefixes every JSON text of an application/json-seq stream.
+const recordSeparator = 0x1E
+
+// writeStream reads the JSON values of a streaming response (newline
+// delimited, concatenated or json-seq) as soon as they arrive and
+// writes each one to streams.Out, applying the query and output format.
+//
+// When until is set, it stops at the first event that satisfies it and
+// returns ErrStreamEnded if the stream ends first; when failIf is set,
+// the first event that satisfies it stops the stream with a *retry.FailError.
+func (hc *restClientImpl) writeStream(res *http.Response, streams IOStreams) error {
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return dumpResponse(res, streams.Out, streams.Err)
+	}
+
+	out := streams.Out
+	if out == nil {
+		out = io.Discard
+	}
+
+	dec := json.NewDecoder(&rsFilter{r: res.Body})
+	for n := 0; ; n++ {
+		var event json.RawMessage
+		if err := dec.Decode(&event); err != nil {
+			if err == io.EOF {
+				break
+			}
+			return fmt.Errorf("unable to decode stream event: %w", err)
+		}
+
+		if hc.output == OutputYAML && n > 0 {
+			if _, err := fmt.Fprintln(out, "---"); err != nil {
+				return err
+			}
+		}
+
+		if err := hc.writeEvent(out, event); err != nil {
+			return err
+		}
+
+		if hc.failIf != "" {
+			failed, err := jq.EvalBoolExpr(event, hc.failIf)
+			if err != nil {
+				return err
+			}
+			if failed {
+				return &retry.FailError{Expr: hc.failIf, Body: event}
+			}
+		}
+
+		if hc.until != "" {
+			ok, err := jq.EvalBoolExpr(event, hc.until)
+			if err != nil {
+				return err
+			}
+			if ok {
+				return nil
+			}
+		}
+	}
+
+	if hc.until != "" {
+		return ErrStreamEnded
+	}
+
+	return nil
+}
+
+func (hc *restClientImpl) writeEvent(out io.Writer, event []byte) error {
+	if hc.query != "" {
+		return writeQueryResults(out, event, hc.query, hc.rawOutput, hc.output)
+	}
+
+	if hc.output == OutputRaw || hc.output == "" {
+		// one event per line, whatever the server indentation
+		var buf bytes.Buffer
+		if err := json.Compact(&buf, event); err != nil {
+			return err
+		}
+		buf.WriteByte('\n')
+		_, err := buf.WriteTo(out)
+		return err
+	}
+
+	return writeFormatted(out, event, hc.output)
+}
+
+// rsFilter turns the json-seq record separators into whitespace,
+// so that the JSON decoder can read the texts one after the other.
+type rsFilter struct {
+	r io.Reader
+}
+
+func (f *rsFilter) Read(p []byte) (int, error) {
+	n, err := f.r.Read(p)
+	for i := range p[:n] {
+		if p[i] == recordSeparator {
+			p[i] = '\n'
+		}
+	}
+	return n, err
+}
@@ -0,0 +1,118 @@
+package restclient
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/lucasepe/resto/internal/util/retry"
+)
+
+// newWatchServer sends the events and keeps the response open
+// until the client goes away, like a Kubernetes watch.
+func newWatchServer(contentType string, events ...string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", contentType)
+		w.WriteHeader(http.StatusOK)
+		for _, el := range events {
+			fmt.Fprintln(w, el)
+			w.(http.Flusher).Flush()
+		}
+		<-r.Context().Done()
+	}))
+}
+
+func TestRESTClient_DoStream(t *testing.T) {
+	events := []string{
+		`{"type": "ADDED", "object": {"phase": "Pending"}}`,
+		`{"type": "MODIFIED", "object": {"phase": "Running"}}`,
+		`{"type": "MODIFIED", "object": {"phase": "Succeeded"}}`,
+	}
+
+	tests := []struct {
+		name        string
+		contentType string
+		opts        RequestOptions
+		want        string
+		wantErr     error
+	}{
+		{
+			name:        "ndjson until match",
+			contentType: "application/x-ndjson",
+			opts:        RequestOptions{Until: `.object.phase == "Running"`},
+			want: `{"type":"ADDED","object":{"phase":"Pending"}}` + "\n" +
+				`{"type":"MODIFIED","object":{"phase":"Running"}}` + "\n",
+		},
+		{
+			name:        "watch with query",
+			contentType: "application/json",
+			opts: RequestOptions{
+				Stream:    true,
+				Until:     `.object.phase == "Running"`,
+				Query:     ".object.phase",
+				RawOutput: true,
+			},
+			want: "Pending\nRunning\n",
+		},
+		{
+			name:        "fail-if match",
+			contentType: "application/json",
+			opts: RequestOptions{
+				Stream: true,
+				Until:  `.object.phase == "Succeeded"`,
+				FailIf: `.object.phase == "Running"`,
+				Query:  ".type",
+			},
+			want:    "\"ADDED\"\n\"MODIFIED\"\n",
+			wantErr: &retry.FailError{},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			ts := newWatchServer(tc.contentType, events...)
+			defer ts.Close()
+
+			var out bytes.Buffer
+			tc.opts.BaseURL = ts.URL
+
+			err := New(tc.opts).Do(context.Background(), ts.Client(), IOStreams{Out: &out})
+			if tc.wantErr == nil && err != nil {
+				t.Fatalf("Do() error = %v", err)
+			}
+			if tc.wantErr != nil {
+				var failErr *retry.FailError
+				if !errors.As(err, &failErr) {
+					t.Fatalf("Do() error = %v, want %T", err, tc.wantErr)
+				}
+			}
+
+			if got := out.String(); got != tc.want {
+				t.Errorf("got %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestRESTClient_DoStreamEnded(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json-seq")
+		fmt.Fprint(w, "\x1e{\"n\": 1}\n\x1e{\"n\": 2}\n")
+	}))
+	defer ts.Close()
+
+	var out bytes.Buffer
+	err := New(RequestOptions{BaseURL: ts.URL, Until: ".n > 2"}).
+		Do(context.Background(), ts.Client(), IOStreams{Out: &out})
+	if !errors.Is(err, ErrStreamEnded) {
+		t.Fatalf("Do() error = %v, want %v", err, ErrStreamEnded)
+	}
+
+	if got, want := out.String(), "{\"n\":1}\n{\"n\":2}\n"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
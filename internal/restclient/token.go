@@ -0,0 +1,126 @@
+package restclient
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultTokenCommandTTL is how long the output of the token
+// command is reused when no TTL is configured.
+const DefaultTokenCommandTTL = time.Minute
+
+// tokenSource returns the bearer token to send with each attempt.
+type tokenSource interface {
+	Token(ctx context.Context) (string, error)
+}
+
+// newTokenSource returns the token source for the configuration:
+// a token file or a token command, if any, nil otherwise.
+func newTokenSource(cfg *Config) tokenSource {
+	switch {
+	case cfg.TokenFile != "":
+		return &fileTokenSource{path: cfg.TokenFile}
+	case cfg.TokenCommand != "":
+		ttl := cfg.TokenCommandTTL
+		if ttl <= 0 {
+			ttl = DefaultTokenCommandTTL
+		}
+		return &commandTokenSource{command: cfg.TokenCommand, ttl: ttl, now: time.Now}
+	default:
+		return nil
+	}
+}
+
+// fileTokenSource reads the token from a file, again whenever the file
+// is modified (e.g. Kubernetes projected service account tokens).
+type fileTokenSource struct {
+	path string
+
+	mu      sync.Mutex
+	modTime time.Time
+	size    int64
+	token   string `datapolicy:"token"`
+}
+
+func (ts *fileTokenSource) Token(_ context.Context) (string, error) {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+
+	fi, err := os.Stat(ts.path)
+	if err != nil {
+		return "", fmt.Errorf("unable to read token file: %w", err)
+	}
+
+	if ts.token != "" && fi.ModTime().Equal(ts.modTime) && fi.Size() == ts.size {
+		return ts.token, nil
+	}
+
+	bin, err := os.ReadFile(ts.path)
+	if err != nil {
+		return "", fmt.Errorf("unable to read token file: %w", err)
+	}
+
+	token := strings.TrimSpace(string(bin))
+	if token == "" {
+		return "", fmt.Errorf("token file %q is empty", ts.path)
+	}
+
+	ts.token, ts.modTime, ts.size = token, fi.ModTime(), fi.Size()
+
+	return ts.token, nil
+}
+
+// commandTokenSource runs a shell command (e.g. a cloud CLI) and uses
+// its trimmed standard output as token, for at most ttl.
+type commandTokenSource struct {
+	command string
+	ttl     time.Duration
+	now     func() time.Time
+
+	mu     sync.Mutex
+	token  string `datapolicy:"token"`
+	expiry time.Time
+}
+
+func (ts *commandTokenSource) Token(ctx context.Context) (string, error) {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+
+	if ts.token != "" && ts.now().Before(ts.expiry) {
+		return ts.token, nil
+	}
+
+	var stdout, stderr bytes.Buffer
+	cmd := shellCommand(ctx, ts.command)
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", fmt.Errorf("token command failed: %w: %s", err, msg)
+		}
+		return "", fmt.Errorf("token command failed: %w", err)
+	}
+
+	token := strings.TrimSpace(stdout.String())
+	if token == "" {
+		return "", fmt.Errorf("token command printed no token")
+	}
+
+	ts.token, ts.expiry = token, ts.now().Add(ts.ttl)
+
+	return ts.token, nil
+}
+
+func shellCommand(ctx context.Context, command string) *exec.Cmd {
+	if runtime.GOOS == "windows" {
+		return exec.CommandContext(ctx, "cmd", "/C", command)
+	}
+	return exec.CommandContext(ctx, "sh", "-c", command)
+}
@@ -0,0 +1,109 @@
+package restclient
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFileTokenSource(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "token")
+	require.NoError(t, os.WriteFile(filename, []byte("token-1\n"), 0600))
+
+	ts := &fileTokenSource{path: filename}
+
+	got, err := ts.Token(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "token-1", got)
+
+	// rotated, as the kubelet does with projected tokens
+	require.NoError(t, os.WriteFile(filename, []byte("token-2\n"), 0600))
+	later := time.Now().Add(time.Minute)
+	require.NoError(t, os.Chtimes(filename, later, later))
+
+	got, err = ts.Token(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "token-2", got)
+
+	require.NoError(t, os.Remove(filename))
+	_, err = ts.Token(context.Background())
+	assert.ErrorContains(t, err, "unable to read token file")
+}
+
+func TestCommandTokenSource(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses a POSIX shell")
+	}
+
+	counter := filepath.Join(t.TempDir(), "counter")
+
+	now := time.Now()
+	ts := &commandTokenSource{
+		// prints token-1, token-2, ... one more on each run
+		command: `echo x >> "` + counter + `" && echo "token-$(wc -l < "` + counter + `" | tr -d ' ')"`,
+		ttl:     time.Minute,
+		now:     func() time.Time { return now },
+	}
+
+	for _, tc := range []struct {
+		elapsed time.Duration
+		want    string
+	}{
+		{0, "token-1"},
+		{30 * time.Second, "token-1"},
+		{31 * time.Second, "token-2"},
+	} {
+		now = now.Add(tc.elapsed)
+		got, err := ts.Token(context.Background())
+		require.NoError(t, err)
+		assert.Equal(t, tc.want, got)
+	}
+}
+
+func TestCommandTokenSource_Error(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses a POSIX shell")
+	}
+
+	ts := &commandTokenSource{command: "echo not logged in >&2; exit 1", ttl: time.Minute, now: time.Now}
+
+	_, err := ts.Token(context.Background())
+	assert.ErrorContains(t, err, "token command failed: exit status 1: not logged in")
+
+	ts.command = "true"
+	_, err = ts.Token(context.Background())
+	assert.ErrorContains(t, err, "token command printed no token")
+}
+
+func TestBearerAuthRoundTripper_TokenSource(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "token")
+	require.NoError(t, os.WriteFile(filename, []byte("token-1"), 0600))
+
+	var got []string
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		got = append(got, r.Header.Get("Authorization"))
+	})
+
+	rt := &bearerAuthRoundTripper{
+		source: newTokenSource(&Config{TokenFile: filename}),
+		next:   &mockRoundTripper{mux: mux},
+	}
+
+	for _, token := range []string{"token-1", "token-22"} {
+		require.NoError(t, os.WriteFile(filename, []byte(token), 0600))
+
+		req, _ := http.NewRequest("GET", "http://example.com/", nil)
+		_, err := rt.RoundTrip(req)
+		require.NoError(t, err)
+	}
+
+	assert.Equal(t, []string{"Bearer token-1", "Bearer token-22"}, got)
+}
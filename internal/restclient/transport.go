@@ -8,6 +8,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"log/slog"
 	"net"
 	"net/http"
 	"net/http/httputil"
@@ -15,6 +16,9 @@ import (
 	"os"
 	"strings"
 	"time"
+
+	ioutil "github.com/lucasepe/resto/internal/util/io"
+	"github.com/lucasepe/resto/internal/util/redact"
 )
 
 func tlsConfigFor(ep *Config) (http.RoundTripper, error) {
@@ -29,37 +33,45 @@ func tlsConfigFor(ep *Config) (http.RoundTripper, error) {
 		res.Proxy = http.ProxyURL(u)
 	}
 
-	caCertPool := x509.NewCertPool()
-
-	if len(ep.CertificateAuthorityData) > 0 {
-		caData, err := base64.StdEncoding.DecodeString(ep.CertificateAuthorityData)
-		if err != nil {
-			return nil, fmt.Errorf("unable to decode certificate authority data")
-		}
-
-		caCertPool.AppendCertsFromPEM(caData)
-	}
-
 	tlsConfig := &tls.Config{
 		InsecureSkipVerify: ep.Insecure,
-		RootCAs:            caCertPool,
 	}
 	defer func() {
 		res.TLSClientConfig = tlsConfig
 	}()
 
+	if ep.HasCA() {
+		caData, err := pemData(ep.CertificateAuthorityData, ep.CertificateAuthorityFile)
+		if err != nil {
+			return nil, fmt.Errorf("unable to load certificate authority: %w", err)
+		}
+
+		caCertPool := x509.NewCertPool()
+		if ep.SystemRoots {
+			if pool, err := x509.SystemCertPool(); err == nil {
+				caCertPool = pool
+			}
+		}
+
+		if !caCertPool.AppendCertsFromPEM(caData) {
+			return nil, fmt.Errorf("unable to load certificate authority: no PEM certificates found")
+		}
+
+		tlsConfig.RootCAs = caCertPool
+	}
+
 	if !ep.HasCertAuth() {
 		return res, nil
 	}
 
-	certData, err := base64.StdEncoding.DecodeString(ep.ClientCertificateData)
+	certData, err := pemData(ep.ClientCertificateData, ep.ClientCertificateFile)
 	if err != nil {
-		return nil, fmt.Errorf("unable to decode client certificate data")
+		return nil, fmt.Errorf("unable to load client certificate: %w", err)
 	}
 
-	keyData, err := base64.StdEncoding.DecodeString(ep.ClientKeyData)
+	keyData, err := pemData(ep.ClientKeyData, ep.ClientKeyFile)
 	if err != nil {
-		return nil, fmt.Errorf("unable to decode client key data")
+		return nil, fmt.Errorf("unable to load client key: %w", err)
 	}
 
 	cert, err := tls.X509KeyPair(certData, keyData)
@@ -72,6 +84,30 @@ func tlsConfigFor(ep *Config) (http.RoundTripper, error) {
 	return res, nil
 }
 
+// pemData returns the PEM encoded content of data, that may be plain
+// PEM or base64 encoded PEM, or, when data is empty, of the given file.
+func pemData(data, filename string) ([]byte, error) {
+	if data == "" {
+		bin, err := os.ReadFile(filename)
+		if err != nil {
+			return nil, err
+		}
+		return bin, nil
+	}
+
+	if strings.Contains(data, "-----BEGIN ") {
+		return []byte(data), nil
+	}
+
+	// base64 may be wrapped on multiple lines
+	bin, err := base64.StdEncoding.DecodeString(strings.Join(strings.Fields(data), ""))
+	if err != nil {
+		return nil, fmt.Errorf("data is neither PEM nor base64 encoded PEM")
+	}
+
+	return bin, nil
+}
+
 func defaultTransport() *http.Transport {
 	dialer := &net.Dialer{
 		Timeout:   30 * time.Second,
@@ -120,6 +156,8 @@ func (rt *basicAuthRoundTripper) RoundTrip(req *http.Request) (*http.Response, e
 
 type bearerAuthRoundTripper struct {
 	bearer string
+	// source, if set, is asked for a fresh token on every attempt.
+	source tokenSource
 	next   http.RoundTripper
 }
 
@@ -128,8 +166,15 @@ func (rt *bearerAuthRoundTripper) RoundTrip(req *http.Request) (*http.Response,
 		return rt.next.RoundTrip(req)
 	}
 
-	req = cloneRequest(req)
 	token := rt.bearer
+	if rt.source != nil {
+		var err error
+		if token, err = rt.source.Token(req.Context()); err != nil {
+			return nil, err
+		}
+	}
+
+	req = cloneRequest(req)
 
 	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", token))
 	return rt.next.RoundTrip(req)
@@ -138,9 +183,19 @@ func (rt *bearerAuthRoundTripper) RoundTrip(req *http.Request) (*http.Response,
 type verboseRoundTripper struct {
 	v    bool
 	next http.RoundTripper
+	// logger, if set, receives the exchange as debug records.
+	logger *slog.Logger
+	// redactor hides the secrets from the output.
+	redactor *redact.Redactor
+	// stream echoes every response body while it is read (--watch).
+	stream bool
 }
 
 func (vt *verboseRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	if vt.v && vt.logger != nil {
+		return vt.logRoundTrip(req)
+	}
+
 	var reqBody []byte
 	if req.Body != nil {
 		reqBody, _ = io.ReadAll(req.Body)
@@ -150,11 +205,11 @@ func (vt *verboseRoundTripper) RoundTrip(req *http.Request) (*http.Response, err
 	if vt.v {
 		fmt.Fprintln(os.Stderr)
 
-		dumpReq, _ := httputil.DumpRequestOut(req, false)
+		dumpReq, _ := httputil.DumpRequestOut(vt.redactor.Request(req), false)
 		addPrefixToLines(os.Stderr, dumpReq, "> ")
 
 		if len(reqBody) > 0 {
-			fmt.Fprintf(os.Stderr, "\n%s\n", string(reqBody))
+			fmt.Fprintf(os.Stderr, "\n%s\n", string(vt.redactor.Form(vt.redactor.Body(reqBody))))
 		}
 		fmt.Fprint(os.Stderr, "\n\n")
 	}
@@ -164,6 +219,21 @@ func (vt *verboseRoundTripper) RoundTrip(req *http.Request) (*http.Response, err
 		return nil, err
 	}
 
+	// Streams may never end: echo the body while the caller reads it.
+	if vt.v && resp.Body != nil && vt.isStream(resp) {
+		dumpResp, _ := httputil.DumpResponse(vt.redactedResponse(resp), false)
+		addPrefixToLines(os.Stderr, dumpResp, "< ")
+		fmt.Fprintln(os.Stderr)
+
+		out := &lineRedactor{w: os.Stderr, redactor: vt.redactor}
+		resp.Body = &teeReadCloser{
+			Reader: io.TeeReader(resp.Body, out),
+			body:   resp.Body,
+			out:    out,
+		}
+		return resp, nil
+	}
+
 	var respBody []byte
 	if resp.Body != nil {
 		respBody, _ = io.ReadAll(resp.Body)
@@ -171,21 +241,126 @@ func (vt *verboseRoundTripper) RoundTrip(req *http.Request) (*http.Response, err
 	}
 
 	if vt.v {
-		dumpResp, _ := httputil.DumpResponse(resp, false)
+		dumpResp, _ := httputil.DumpResponse(vt.redactedResponse(resp), false)
 		addPrefixToLines(os.Stderr, dumpResp, "< ")
 
 		contentType := resp.Header.Get("Content-Type")
 		fmt.Fprintln(os.Stderr)
 		if strings.Contains(contentType, "application/json") {
-			prettyPrintJSON(respBody)
+			prettyPrintJSON(vt.redactor.Body(respBody))
 		} else {
-			fmt.Fprintln(os.Stderr, string(respBody))
+			fmt.Fprintln(os.Stderr, string(vt.redactor.Form(respBody)))
 		}
 	}
 
 	return resp, nil
 }
 
+// logRoundTrip reports the request and the response as debug records,
+// so that verbose output and structured logs can share stderr.
+func (vt *verboseRoundTripper) logRoundTrip(req *http.Request) (*http.Response, error) {
+	ctx := req.Context()
+
+	var reqBody []byte
+	if req.Body != nil {
+		reqBody, _ = io.ReadAll(req.Body)
+		req.Body = io.NopCloser(bytes.NewReader(reqBody))
+	}
+
+	vt.logger.DebugContext(ctx, "request",
+		"method", req.Method,
+		"url", vt.redactor.URL(req.URL),
+		"headers", vt.redactor.Header(req.Header),
+		"body", string(vt.redactor.Form(vt.redactor.Body(reqBody))))
+
+	resp, err := vt.next.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	attrs := []any{
+		"status", resp.StatusCode,
+		"headers", vt.redactor.Header(resp.Header),
+	}
+
+	// stream bodies are never buffered
+	if resp.Body != nil && !vt.isStream(resp) {
+		respBody, _ := io.ReadAll(resp.Body)
+		resp.Body = io.NopCloser(bytes.NewReader(respBody))
+		attrs = append(attrs, "body", string(vt.redactor.Form(vt.redactor.Body(respBody))))
+	}
+
+	vt.logger.DebugContext(ctx, "response", attrs...)
+
+	return resp, nil
+}
+
+// redactedResponse returns a shallow copy of resp with
+// the sensitive headers redacted, suitable for dumping.
+func (vt *verboseRoundTripper) redactedResponse(resp *http.Response) *http.Response {
+	res := *resp
+	res.Header = vt.redactor.Header(resp.Header)
+	return &res
+}
+
+// isStream reports whether the response must not be buffered: when
+// streaming is requested or the response has a streaming content type.
+func (vt *verboseRoundTripper) isStream(resp *http.Response) bool {
+	return vt.stream || ioutil.IsStreamingContentType(resp.Header.Get("Content-Type"))
+}
+
+type teeReadCloser struct {
+	io.Reader
+	body io.Closer
+	out  *lineRedactor
+}
+
+func (t *teeReadCloser) Close() error {
+	t.out.Flush()
+	return t.body.Close()
+}
+
+// lineRedactor writes the data one line at a time, redacting each one:
+// stream events are newline delimited, as the server-sent events data.
+type lineRedactor struct {
+	w        io.Writer
+	redactor *redact.Redactor
+	buf      []byte
+}
+
+func (lr *lineRedactor) Write(p []byte) (int, error) {
+	lr.buf = append(lr.buf, p...)
+	for {
+		i := bytes.IndexByte(lr.buf, '\n')
+		if i < 0 {
+			break
+		}
+		lr.writeLine(lr.buf[:i+1])
+		lr.buf = lr.buf[i+1:]
+	}
+	return len(p), nil
+}
+
+// Flush writes the last line, even if incomplete.
+func (lr *lineRedactor) Flush() {
+	if len(lr.buf) > 0 {
+		lr.writeLine(lr.buf)
+		lr.buf = nil
+	}
+}
+
+func (lr *lineRedactor) writeLine(line []byte) {
+	content := bytes.TrimRight(line, "\r\n")
+	eol := line[len(content):]
+
+	prefix, data := []byte(nil), content
+	if rest, ok := bytes.CutPrefix(content, []byte("data:")); ok {
+		prefix, data = content[:len("data:")], rest
+	}
+
+	fmt.Fprintf(lr.w, "%s%s%s", prefix, lr.redactor.Body(data), eol)
+}
+
 func prettyPrintJSON(body []byte) {
 	var out bytes.Buffer
 	err := json.Indent(&out, body, "", "  ")
@@ -15,9 +15,13 @@ import (
 	"net"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
+	"github.com/lucasepe/resto/internal/util/redact"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -197,6 +201,220 @@ func TestHTTPSCallSucceedsWithoutInsecureSkipVerify(t *testing.T) {
 	}
 }
 
+func TestHTTPSCallWithPEMFiles(t *testing.T) {
+	certPEM, keyPEM, tlsCert := generateSelfSignedCert()
+
+	dir := t.TempDir()
+	files := map[string][]byte{"ca.pem": certPEM, "cert.pem": certPEM, "key.pem": keyPEM}
+	for name, content := range files {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), content, 0600))
+	}
+
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"message":"success"}`))
+	}))
+	server.TLS = &tls.Config{Certificates: []tls.Certificate{tlsCert}}
+	server.StartTLS()
+	defer server.Close()
+
+	cfg := Config{
+		ServerURL:                server.URL,
+		CertificateAuthorityFile: filepath.Join(dir, "ca.pem"),
+		ClientCertificateFile:    filepath.Join(dir, "cert.pem"),
+		ClientKeyFile:            filepath.Join(dir, "key.pem"),
+		SystemRoots:              true,
+	}
+
+	client, err := HTTPClientForConfig(cfg)
+	require.NoError(t, err)
+
+	resp, err := client.Get(cfg.ServerURL)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+}
+
+func TestTLSConfigFor(t *testing.T) {
+	certPEM, keyPEM, _ := generateSelfSignedCert()
+
+	b64 := base64.StdEncoding.EncodeToString
+
+	tests := []struct {
+		name    string
+		cfg     Config
+		wantErr string
+	}{
+		{
+			name: "plain PEM",
+			cfg: Config{
+				CertificateAuthorityData: string(certPEM),
+				ClientCertificateData:    string(certPEM),
+				ClientKeyData:            string(keyPEM),
+			},
+		},
+		{
+			name: "base64 wrapped on multiple lines",
+			cfg: Config{
+				CertificateAuthorityData: strings.Join(chunks(b64(certPEM), 64), "\n"),
+			},
+		},
+		{
+			name:    "invalid base64",
+			cfg:     Config{CertificateAuthorityData: "not-base64!"},
+			wantErr: "neither PEM nor base64",
+		},
+		{
+			name:    "no certificates",
+			cfg:     Config{CertificateAuthorityData: b64([]byte("dev-ca"))},
+			wantErr: "no PEM certificates found",
+		},
+		{
+			name:    "missing file",
+			cfg:     Config{CertificateAuthorityFile: filepath.Join(t.TempDir(), "missing.pem")},
+			wantErr: "unable to load certificate authority",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			rt, err := tlsConfigFor(&tc.cfg)
+			if tc.wantErr != "" {
+				assert.ErrorContains(t, err, tc.wantErr)
+				return
+			}
+			require.NoError(t, err)
+
+			tlsConfig := rt.(*http.Transport).TLSClientConfig
+			require.NotNil(t, tlsConfig.RootCAs)
+			assert.Equal(t, tc.cfg.HasCertAuth(), len(tlsConfig.Certificates) == 1)
+		})
+	}
+}
+
+func TestTLSConfigForWithoutCA(t *testing.T) {
+	rt, err := tlsConfigFor(&Config{})
+	require.NoError(t, err)
+
+	// nil means the system roots
+	assert.Nil(t, rt.(*http.Transport).TLSClientConfig.RootCAs)
+}
+
+func TestVerboseRoundTripper_Chunked(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"token":`)
+		w.(http.Flusher).Flush()
+		fmt.Fprint(w, `"s3cr3t", "name": "demo"}`)
+	}))
+	defer srv.Close()
+
+	tests := []struct {
+		name   string
+		stream bool
+		want   string
+	}{
+		{name: "buffered", want: "{\n  \"token\": \"REDACTED\",\n  \"name\": \"demo\"\n}"},
+		{name: "stream", stream: true, want: `{"token":"REDACTED","name":"demo"}`},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			vt := &verboseRoundTripper{
+				v:        true,
+				next:     http.DefaultTransport,
+				redactor: redact.New(nil, nil),
+				stream:   tc.stream,
+			}
+
+			var body []byte
+			stderr := captureStderr(t, func() {
+				req, _ := http.NewRequest(http.MethodGet, srv.URL, nil)
+				resp, err := vt.RoundTrip(req)
+				require.NoError(t, err)
+				require.Equal(t, int64(-1), resp.ContentLength)
+
+				body, err = io.ReadAll(resp.Body)
+				require.NoError(t, err)
+				require.NoError(t, resp.Body.Close())
+			})
+
+			assert.Equal(t, `{"token":"s3cr3t", "name": "demo"}`, string(body))
+			assert.NotContains(t, stderr, "s3cr3t")
+			assert.Contains(t, stderr, tc.want)
+		})
+	}
+}
+
+func TestVerboseRoundTripper_FormBody(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/x-www-form-urlencoded")
+		fmt.Fprint(w, "access_token=s3cr3t&token_type=bearer")
+	}))
+	defer srv.Close()
+
+	vt := &verboseRoundTripper{
+		v:        true,
+		next:     http.DefaultTransport,
+		redactor: redact.New(nil, nil),
+	}
+
+	stderr := captureStderr(t, func() {
+		req, _ := http.NewRequest(http.MethodPost, srv.URL,
+			strings.NewReader("grant_type=password&password=p4ssw0rd"))
+		resp, err := vt.RoundTrip(req)
+		require.NoError(t, err)
+		require.NoError(t, resp.Body.Close())
+	})
+
+	assert.NotContains(t, stderr, "s3cr3t")
+	assert.NotContains(t, stderr, "p4ssw0rd")
+	assert.Contains(t, stderr, "grant_type=password&password=REDACTED")
+	assert.Contains(t, stderr, "access_token=REDACTED&token_type=bearer")
+}
+
+func TestLineRedactor(t *testing.T) {
+	var out strings.Builder
+	lr := &lineRedactor{w: &out, redactor: redact.New(nil, nil)}
+
+	for _, el := range chunks("{\"password\": \"p\"}\ndata: {\"token\": \"t\"}\r\nplain\n{\"client_secret\": 1}", 5) {
+		fmt.Fprint(lr, el)
+	}
+	lr.Flush()
+
+	assert.Equal(t, "{\"password\":\"REDACTED\"}\ndata:{\"token\":\"REDACTED\"}\r\nplain\n{\"client_secret\":\"REDACTED\"}", out.String())
+}
+
+// captureStderr returns what fn writes to os.Stderr.
+func captureStderr(t *testing.T, fn func()) string {
+	t.Helper()
+
+	r, w, err := os.Pipe()
+	require.NoError(t, err)
+
+	old := os.Stderr
+	os.Stderr = w
+	defer func() { os.Stderr = old }()
+
+	done := make(chan []byte)
+	go func() {
+		buf, _ := io.ReadAll(r)
+		done <- buf
+	}()
+
+	fn()
+	w.Close()
+
+	return string(<-done)
+}
+
+func chunks(s string, size int) (res []string) {
+	for len(s) > size {
+		res, s = append(res, s[:size]), s[size:]
+	}
+	return append(res, s)
+}
+
 func generateSelfSignedCert() (certPEM []byte, keyPEM []byte, tlsCert tls.Certificate) {
 	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
 	if err != nil {
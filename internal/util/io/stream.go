@@ -0,0 +1,28 @@
+package io
+
+import (
+	"mime"
+	"strings"
+)
+
+// IsStreamingContentType reports whether the media type denotes a
+// long-lived response whose body is made of a sequence of events,
+// like newline-delimited JSON or server-sent events.
+func IsStreamingContentType(contentType string) bool {
+	media, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+
+	switch strings.ToLower(media) {
+	case "application/x-ndjson",
+		"application/ndjson",
+		"application/jsonl",
+		"application/json-seq",
+		"application/stream+json",
+		"text/event-stream":
+		return true
+	default:
+		return false
+	}
+}
@@ -7,6 +7,21 @@ import (
 	"github.com/itchyny/gojq"
 )
 
+// Error is returned when the input is not valid JSON or when
+// a JQ expression cannot be parsed or evaluated.
+type Error struct {
+	Expr string
+	Err  error
+}
+
+func (e *Error) Error() string {
+	return e.Err.Error()
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
 // EvalBoolExpr evaluates a JQ expression against the given JSON input and returns a boolean result.
 //
 // inputJSON must be a valid JSON byte slice.
@@ -24,16 +39,16 @@ import (
 //
 // Returns:
 //   - (bool, nil) if the expression returns true or false successfully
-//   - (false, error) if there is a JSON parsing error, JQ parsing error, or if the expression does not return a boolean
+//   - (false, *Error) if there is a JSON parsing error, JQ parsing error, or if the expression does not return a boolean
 func EvalBoolExpr(inputJSON []byte, jqExpr string) (bool, error) {
 	var data any
 	if err := json.Unmarshal(inputJSON, &data); err != nil {
-		return false, fmt.Errorf("invalid JSON: %w", err)
+		return false, &Error{Expr: jqExpr, Err: fmt.Errorf("invalid JSON: %w", err)}
 	}
 
 	query, err := gojq.Parse(jqExpr)
 	if err != nil {
-		return false, fmt.Errorf("invalid JQ expression: %w", err)
+		return false, &Error{Expr: jqExpr, Err: fmt.Errorf("invalid JQ expression: %w", err)}
 	}
 
 	iter := query.Run(data)
@@ -43,15 +58,49 @@ func EvalBoolExpr(inputJSON []byte, jqExpr string) (bool, error) {
 			break
 		}
 		if err, isErr := v.(error); isErr {
-			return false, fmt.Errorf("evaluation error: %w", err)
+			return false, &Error{Expr: jqExpr, Err: fmt.Errorf("evaluation error: %w", err)}
 		}
 		if b, ok := v.(bool); ok {
 			return b, nil
 		} else {
 			// Fail if result is not boolean
-			return false, fmt.Errorf("expression did not return a boolean: got %T (%v)", v, v)
+			return false, &Error{Expr: jqExpr, Err: fmt.Errorf("expression did not return a boolean: got %T (%v)", v, v)}
 		}
 	}
 
 	return false, nil
 }
+
+// Eval evaluates a JQ expression against the given JSON input and returns
+// all the values produced by the expression, in order.
+//
+// Example:
+//
+//	res, err := Eval(data, ".items[].metadata.name")
+func Eval(inputJSON []byte, jqExpr string) ([]any, error) {
+	var data any
+	if err := json.Unmarshal(inputJSON, &data); err != nil {
+		return nil, &Error{Expr: jqExpr, Err: fmt.Errorf("invalid JSON: %w", err)}
+	}
+
+	query, err := gojq.Parse(jqExpr)
+	if err != nil {
+		return nil, &Error{Expr: jqExpr, Err: fmt.Errorf("invalid JQ expression: %w", err)}
+	}
+
+	res := []any{}
+
+	iter := query.Run(data)
+	for {
+		v, ok := iter.Next()
+		if !ok {
+			break
+		}
+		if err, isErr := v.(error); isErr {
+			return nil, &Error{Expr: jqExpr, Err: fmt.Errorf("evaluation error: %w", err)}
+		}
+		res = append(res, v)
+	}
+
+	return res, nil
+}
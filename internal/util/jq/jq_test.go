@@ -1,6 +1,7 @@
 package jq
 
 import (
+	"reflect"
 	"testing"
 )
 
@@ -91,3 +92,56 @@ func TestEvaluateBool(t *testing.T) {
 		})
 	}
 }
+
+func TestEval(t *testing.T) {
+	tests := []struct {
+		name      string
+		jsonInput []byte
+		expr      string
+		want      []any
+		wantErr   bool
+	}{
+		{
+			name:      "Single value",
+			jsonInput: podJSON,
+			expr:      `.status.phase`,
+			want:      []any{"Running"},
+		},
+		{
+			name:      "Multiple values",
+			jsonInput: podJSON,
+			expr:      `.status.conditions[].type`,
+			want:      []any{"Initialized", "Ready", "ContainersReady", "PodScheduled"},
+		},
+		{
+			name:      "No values",
+			jsonInput: podJSON,
+			expr:      `empty`,
+			want:      []any{},
+		},
+		{
+			name:      "JQ syntax error",
+			jsonInput: podJSON,
+			expr:      `.status[`,
+			wantErr:   true,
+		},
+		{
+			name:      "Evaluation error",
+			jsonInput: podJSON,
+			expr:      `.status.phase | keys`,
+			wantErr:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Eval(tt.jsonInput, tt.expr)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Eval() error = %v, wantErr = %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Eval() = %v, want = %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,257 @@
+// Package redact hides secrets (credentials, tokens, cookies)
+// from the traffic written to verbose output, logs and recordings.
+package redact
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+)
+
+// Placeholder replaces the redacted values.
+const Placeholder = "REDACTED"
+
+// DefaultHeaders are always redacted.
+var DefaultHeaders = []string{
+	"Authorization",
+	"Cookie",
+	"Proxy-Authorization",
+	"Set-Cookie",
+}
+
+// DefaultFields are the body fields (JSON or form-encoded) always redacted.
+var DefaultFields = []string{
+	"access_token",
+	"client_secret",
+	"id_token",
+	"password",
+	"refresh_token",
+	"token",
+}
+
+// Redactor replaces the values of sensitive headers and body fields.
+//
+// A nil *Redactor redacts nothing: all its methods return their
+// input unchanged.
+type Redactor struct {
+	headers map[string]bool
+	fields  map[string]bool
+}
+
+// New returns a Redactor for the default headers and fields plus the
+// given ones. Header names are case insensitive, as field names.
+func New(headers, fields []string) *Redactor {
+	res := &Redactor{
+		headers: map[string]bool{},
+		fields:  map[string]bool{},
+	}
+
+	for _, el := range slices.Concat(DefaultHeaders, headers) {
+		if el = strings.TrimSpace(el); el != "" {
+			res.headers[http.CanonicalHeaderKey(el)] = true
+		}
+	}
+
+	for _, el := range slices.Concat(DefaultFields, fields) {
+		if el = strings.TrimSpace(el); el != "" {
+			res.fields[strings.ToLower(el)] = true
+		}
+	}
+
+	return res
+}
+
+// Header returns a copy of h with the sensitive values redacted.
+func (r *Redactor) Header(h http.Header) http.Header {
+	if r == nil || h == nil {
+		return h
+	}
+
+	res := h.Clone()
+	for name, values := range res {
+		if !r.headers[http.CanonicalHeaderKey(name)] {
+			continue
+		}
+		for i := range values {
+			values[i] = Placeholder
+		}
+	}
+
+	return res
+}
+
+// Request returns a shallow copy of req with the sensitive headers
+// and the URL credentials redacted, suitable for dumping.
+func (r *Redactor) Request(req *http.Request) *http.Request {
+	if r == nil {
+		return req
+	}
+
+	res := req.Clone(req.Context())
+	res.Header = r.Header(req.Header)
+	if req.URL.User != nil {
+		res.URL.User = url.UserPassword(req.URL.User.Username(), Placeholder)
+	}
+
+	return res
+}
+
+// URL returns the URL with the password, if any, redacted.
+func (r *Redactor) URL(u *url.URL) string {
+	if r == nil || u.User == nil {
+		return u.String()
+	}
+
+	res := *u
+	res.User = url.UserPassword(u.User.Username(), Placeholder)
+	return res.String()
+}
+
+// Body returns the JSON body with the values of the sensitive fields
+// redacted, at any depth. Bodies that are not JSON, or that have
+// nothing to redact, are returned as is.
+func (r *Redactor) Body(body []byte) []byte {
+	if r == nil || len(r.fields) == 0 {
+		return body
+	}
+
+	trimmed := bytes.TrimSpace(body)
+	if len(trimmed) == 0 || (trimmed[0] != '{' && trimmed[0] != '[') || !json.Valid(trimmed) {
+		return body
+	}
+
+	res, changed, err := r.value(trimmed)
+	if err != nil || !changed {
+		return body
+	}
+
+	return res
+}
+
+// Form returns the form-encoded body (e.g. an OAuth2 token response)
+// with the values of the sensitive fields redacted, preserving the
+// order and the encoding of the others. Bodies that do not look
+// form-encoded, or that have nothing to redact, are returned as is.
+func (r *Redactor) Form(body []byte) []byte {
+	if r == nil || len(r.fields) == 0 {
+		return body
+	}
+
+	trimmed := bytes.TrimSpace(body)
+	if len(trimmed) == 0 || trimmed[0] == '{' || trimmed[0] == '[' ||
+		bytes.ContainsAny(trimmed, " \t\r\n") {
+		return body
+	}
+
+	pairs := strings.Split(string(trimmed), "&")
+
+	changed := false
+	for i, el := range pairs {
+		key, _, ok := strings.Cut(el, "=")
+		if !ok {
+			continue
+		}
+
+		name, err := url.QueryUnescape(key)
+		if err != nil {
+			return body
+		}
+
+		if r.fields[strings.ToLower(name)] {
+			pairs[i] = key + "=" + Placeholder
+			changed = true
+		}
+	}
+
+	if !changed {
+		return body
+	}
+
+	return []byte(strings.Join(pairs, "&"))
+}
+
+// value redacts a JSON value, preserving the key order.
+func (r *Redactor) value(raw json.RawMessage) ([]byte, bool, error) {
+	switch raw[0] {
+	case '{':
+		return r.object(raw)
+	case '[':
+		var items []json.RawMessage
+		if err := json.Unmarshal(raw, &items); err != nil {
+			return nil, false, err
+		}
+
+		changed := false
+		for i, el := range items {
+			res, ok, err := r.value(el)
+			if err != nil {
+				return nil, false, err
+			}
+			items[i], changed = res, changed || ok
+		}
+		if !changed {
+			return raw, false, nil
+		}
+
+		res, err := json.Marshal(items)
+		return res, true, err
+	default:
+		return raw, false, nil
+	}
+}
+
+func (r *Redactor) object(raw json.RawMessage) ([]byte, bool, error) {
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	if _, err := dec.Token(); err != nil { // {
+		return nil, false, err
+	}
+
+	var (
+		buf     bytes.Buffer
+		changed bool
+	)
+
+	buf.WriteByte('{')
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return nil, false, err
+		}
+		key := tok.(string)
+
+		var val json.RawMessage
+		if err := dec.Decode(&val); err != nil {
+			return nil, false, err
+		}
+
+		if buf.Len() > 1 {
+			buf.WriteByte(',')
+		}
+		name, _ := json.Marshal(key)
+		buf.Write(name)
+		buf.WriteByte(':')
+
+		if r.fields[strings.ToLower(key)] {
+			buf.WriteString(`"` + Placeholder + `"`)
+			changed = true
+			continue
+		}
+
+		res, ok, err := r.value(val)
+		if err != nil {
+			return nil, false, err
+		}
+		buf.Write(res)
+		changed = changed || ok
+	}
+	buf.WriteByte('}')
+
+	if !changed {
+		return raw, false, nil
+	}
+
+	return buf.Bytes(), true, nil
+}
@@ -0,0 +1,33 @@
+package retry
+
+import "context"
+
+type attemptKey struct{}
+
+// WithAttempt returns a copy of ctx carrying the attempt number
+// of the request, starting from 1.
+func WithAttempt(ctx context.Context, n int) context.Context {
+	return context.WithValue(ctx, attemptKey{}, n)
+}
+
+// AttemptFrom returns the attempt number carried by ctx,
+// or zero if the request is not sent by the retry round tripper.
+func AttemptFrom(ctx context.Context) int {
+	n, _ := ctx.Value(attemptKey{}).(int)
+	return n
+}
+
+type noConditionKey struct{}
+
+// WithoutCondition returns a copy of ctx telling the retry round tripper
+// to retry the request only on the RetryOn failures, without evaluating
+// the until and fail-if expressions on the response.
+func WithoutCondition(ctx context.Context) context.Context {
+	return context.WithValue(ctx, noConditionKey{}, true)
+}
+
+// conditionDisabled reports whether ctx was returned by WithoutCondition.
+func conditionDisabled(ctx context.Context) bool {
+	off, _ := ctx.Value(noConditionKey{}).(bool)
+	return off
+}
@@ -0,0 +1,252 @@
+package retry
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// Clock is the source of time of the retry loop: it tells the time,
+// waits the delays between the attempts and bounds each attempt.
+// RealClock is used when none is given; tests can drive the time
+// by hand with a FakeClock.
+type Clock interface {
+	Now() time.Time
+	After(d time.Duration) <-chan time.Time
+	AfterFunc(d time.Duration, f func()) Timer
+}
+
+// Timer is a timer started by Clock.AfterFunc.
+type Timer interface {
+	// Stop prevents the timer from firing; it returns false
+	// if the timer already fired or was already stopped.
+	Stop() bool
+}
+
+// RealClock returns the Clock of the time package.
+func RealClock() Clock {
+	return realClock{}
+}
+
+func clockOrReal(c Clock) Clock {
+	if c == nil {
+		return RealClock()
+	}
+	return c
+}
+
+type realClock struct{}
+
+func (realClock) Now() time.Time {
+	return time.Now()
+}
+
+func (realClock) After(d time.Duration) <-chan time.Time {
+	return time.After(d)
+}
+
+func (realClock) AfterFunc(d time.Duration, f func()) Timer {
+	return time.AfterFunc(d, f)
+}
+
+// FakeClock is a Clock whose time only moves when told to (see Advance).
+// It records every delay waited through After, so that tests can
+// assert the exact sequence of waits of a retry loop.
+type FakeClock struct {
+	mu      sync.Mutex
+	now     time.Time
+	timers  []*fakeTimer
+	waits   []time.Duration
+	started chan struct{}
+}
+
+// NewFakeClock returns a FakeClock set at the given time.
+func NewFakeClock(now time.Time) *FakeClock {
+	return &FakeClock{
+		now:     now,
+		started: make(chan struct{}, 1),
+	}
+}
+
+func (c *FakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *FakeClock) After(d time.Duration) <-chan time.Time {
+	ch := make(chan time.Time, 1)
+
+	c.mu.Lock()
+	c.waits = append(c.waits, d)
+	c.mu.Unlock()
+
+	c.start(&fakeTimer{clock: c, ch: ch}, d)
+	return ch
+}
+
+// AfterFunc calls f once the clock has been advanced by d.
+// Unlike time.AfterFunc, f runs in the goroutine calling Advance.
+func (c *FakeClock) AfterFunc(d time.Duration, f func()) Timer {
+	t := &fakeTimer{clock: c, fn: f}
+	c.start(t, d)
+	return t
+}
+
+// Started returns a channel that receives when a timer is started
+// (a wait through After included). Close starts may be coalesced.
+func (c *FakeClock) Started() <-chan struct{} {
+	return c.started
+}
+
+// Pending returns the number of timers not yet fired or stopped.
+func (c *FakeClock) Pending() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.timers)
+}
+
+// Waits returns the delays waited through After, in order.
+func (c *FakeClock) Waits() []time.Duration {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]time.Duration(nil), c.waits...)
+}
+
+// Run calls fn advancing the clock to every wait it starts through
+// After, as if the time flew, until fn returns. The timers started by
+// AfterFunc (e.g. the attempt timeouts) fire only if due meanwhile:
+// they bound work that runs in real time.
+func (c *FakeClock) Run(fn func()) {
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		fn()
+	}()
+
+	for {
+		select {
+		case <-done:
+			return
+		case <-c.started:
+			for {
+				if !c.advanceToNextWait() {
+					break
+				}
+			}
+		}
+	}
+}
+
+// Advance moves the clock forward by d, firing the timers
+// that expire meanwhile in order.
+func (c *FakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	c.now = c.now.Add(d)
+
+	var due, rest []*fakeTimer
+	for _, t := range c.timers {
+		if t.at.After(c.now) {
+			rest = append(rest, t)
+		} else {
+			due = append(due, t)
+		}
+	}
+	c.timers = rest
+	c.mu.Unlock()
+
+	sort.SliceStable(due, func(i, j int) bool {
+		return due[i].at.Before(due[j].at)
+	})
+	for _, t := range due {
+		t.fire()
+	}
+}
+
+// AdvanceToNext moves the clock forward to the first pending timer
+// and fires it; it returns the time moved and false if none is pending.
+func (c *FakeClock) AdvanceToNext() (time.Duration, bool) {
+	c.mu.Lock()
+	if len(c.timers) == 0 {
+		c.mu.Unlock()
+		return 0, false
+	}
+	next := c.timers[0].at
+	for _, t := range c.timers[1:] {
+		if t.at.Before(next) {
+			next = t.at
+		}
+	}
+	d := next.Sub(c.now)
+	c.mu.Unlock()
+
+	c.Advance(d)
+	return d, true
+}
+
+// advanceToNextWait moves the clock forward to the first pending
+// wait started by After; it returns false if none is pending.
+func (c *FakeClock) advanceToNextWait() bool {
+	c.mu.Lock()
+	var next *fakeTimer
+	for _, t := range c.timers {
+		if t.ch != nil && (next == nil || t.at.Before(next.at)) {
+			next = t
+		}
+	}
+	if next == nil {
+		c.mu.Unlock()
+		return false
+	}
+	d := next.at.Sub(c.now)
+	c.mu.Unlock()
+
+	c.Advance(d)
+	return true
+}
+
+func (c *FakeClock) start(t *fakeTimer, d time.Duration) {
+	c.mu.Lock()
+	t.at = c.now.Add(d)
+	if d <= 0 {
+		c.mu.Unlock()
+		t.fire()
+		return
+	}
+	c.timers = append(c.timers, t)
+	c.mu.Unlock()
+
+	select {
+	case c.started <- struct{}{}:
+	default:
+	}
+}
+
+type fakeTimer struct {
+	clock *FakeClock
+	at    time.Time
+	ch    chan time.Time
+	fn    func()
+}
+
+func (t *fakeTimer) fire() {
+	if t.fn != nil {
+		t.fn()
+		return
+	}
+	t.ch <- t.at
+}
+
+func (t *fakeTimer) Stop() bool {
+	c := t.clock
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for i, it := range c.timers {
+		if it == t {
+			c.timers = append(c.timers[:i], c.timers[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,244 @@
+package retry
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// drive runs fn on the fake clock (see FakeClock.Run),
+// failing the test if it does not return in time.
+func drive(t *testing.T, clock *FakeClock, fn func()) {
+	t.Helper()
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		clock.Run(fn)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("fn did not return on the fake clock within 5s")
+	}
+}
+
+// waits runs a retry loop on a fake clock and returns the delays
+// waited between the attempts.
+func waits(t *testing.T, opts RetryOptions, st Strategy, fn RetryFunc) ([]time.Duration, error) {
+	t.Helper()
+
+	clock := NewFakeClock(time.Unix(0, 0))
+	opts.Clock = clock
+
+	var err error
+	drive(t, clock, func() {
+		err = NewRetrier(opts).Retry(context.Background(), st, fn)
+	})
+
+	return clock.Waits(), err
+}
+
+func TestFakeClock(t *testing.T) {
+	start := time.Unix(0, 0)
+	clock := NewFakeClock(start)
+
+	var fired []string
+	clock.AfterFunc(2*time.Second, func() { fired = append(fired, "2s") })
+	clock.AfterFunc(time.Second, func() { fired = append(fired, "1s") })
+	stopped := clock.AfterFunc(time.Second, func() { fired = append(fired, "stopped") })
+	ch := clock.After(3 * time.Second)
+
+	require.True(t, stopped.Stop())
+	require.False(t, stopped.Stop())
+	require.Equal(t, 3, clock.Pending())
+
+	clock.Advance(500 * time.Millisecond)
+	require.Empty(t, fired)
+
+	clock.Advance(2 * time.Second)
+	require.Equal(t, []string{"1s", "2s"}, fired)
+	require.Equal(t, start.Add(2500*time.Millisecond), clock.Now())
+
+	d, ok := clock.AdvanceToNext()
+	require.True(t, ok)
+	require.Equal(t, 500*time.Millisecond, d)
+	require.Equal(t, start.Add(3*time.Second), <-ch)
+
+	_, ok = clock.AdvanceToNext()
+	require.False(t, ok)
+	require.Equal(t, []time.Duration{3 * time.Second}, clock.Waits())
+}
+
+func TestRetrier_Waits(t *testing.T) {
+	const ms = time.Millisecond
+
+	opts := RetryOptions{
+		InitialDelay: 100 * ms,
+		MaxDelay:     time.Second,
+		MaxAttempts:  6,
+	}
+
+	never := func() (bool, error) {
+		return false, nil
+	}
+
+	tests := []struct {
+		name    string
+		backoff string
+		jitter  time.Duration
+		fn      RetryFunc
+		want    []time.Duration
+		wantErr error
+	}{
+		{
+			name:    "constant",
+			backoff: BackoffConstant,
+			want:    []time.Duration{100 * ms, 100 * ms, 100 * ms, 100 * ms, 100 * ms},
+			wantErr: ErrExhausted,
+		},
+		{
+			name:    "linear",
+			backoff: BackoffLinear,
+			want:    []time.Duration{100 * ms, 200 * ms, 300 * ms, 400 * ms, 500 * ms},
+			wantErr: ErrExhausted,
+		},
+		{
+			name:    "exponential",
+			backoff: BackoffExponential,
+			want:    []time.Duration{100 * ms, 200 * ms, 400 * ms, 800 * ms, time.Second},
+			wantErr: ErrExhausted,
+		},
+		{
+			name:    "exponential with jitter",
+			backoff: BackoffExponential,
+			jitter:  100 * ms,
+			want:    []time.Duration{100 * ms, 250 * ms, 550 * ms, time.Second, time.Second},
+			wantErr: ErrExhausted,
+		},
+		{
+			name:    "fibonacci",
+			backoff: BackoffFibonacci,
+			want:    []time.Duration{100 * ms, 100 * ms, 200 * ms, 300 * ms, 500 * ms},
+			wantErr: ErrExhausted,
+		},
+		{
+			name:    "decorrelated jitter",
+			backoff: BackoffDecorrelatedJitter,
+			want:    []time.Duration{100 * ms, 200 * ms, 350 * ms, 575 * ms, 912500 * time.Microsecond},
+			wantErr: ErrExhausted,
+		},
+		{
+			name:    "full jitter",
+			backoff: BackoffFullJitter,
+			want:    []time.Duration{100 * ms, 100 * ms, 200 * ms, 400 * ms, 500 * ms},
+			wantErr: ErrExhausted,
+		},
+		{
+			name:    "no wait after success",
+			backoff: BackoffExponential,
+			fn:      succeedAt(3),
+			want:    []time.Duration{100 * ms, 200 * ms},
+		},
+		{
+			name:    "delay hint",
+			backoff: BackoffExponential,
+			fn: func() (bool, error) {
+				return false, After(30 * ms)
+			},
+			want:    []time.Duration{30 * ms, 30 * ms, 30 * ms, 30 * ms, 30 * ms},
+			wantErr: ErrExhausted,
+		},
+		{
+			name:    "delay hint clamped to max delay",
+			backoff: BackoffExponential,
+			fn: func() (bool, error) {
+				return false, After(time.Hour)
+			},
+			want:    []time.Duration{time.Second, time.Second, time.Second, time.Second, time.Second},
+			wantErr: ErrExhausted,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			opts := opts
+			opts.Backoff = tc.backoff
+			opts.MaxJitter = tc.jitter
+
+			st, err := newStrategy(opts, &fixedSource{values: []float64{0.5}})
+			require.NoError(t, err)
+
+			fn := tc.fn
+			if fn == nil {
+				fn = never
+			}
+
+			got, err := waits(t, opts, st, fn)
+			if tc.wantErr != nil {
+				require.ErrorIs(t, err, tc.wantErr)
+			} else {
+				require.NoError(t, err)
+			}
+			require.Equal(t, tc.want, got)
+		})
+	}
+}
+
+func TestRetrier_WaitCanceled(t *testing.T) {
+	clock := NewFakeClock(time.Unix(0, 0))
+	r := NewRetrier(RetryOptions{
+		InitialDelay: time.Second,
+		MaxDelay:     time.Second,
+		MaxAttempts:  3,
+		Clock:        clock,
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	errc := make(chan error, 1)
+	go func() {
+		errc <- r.Retry(ctx, Constant(), func() (bool, error) {
+			return false, nil
+		})
+	}()
+
+	<-clock.Started()
+	cancel()
+
+	require.ErrorIs(t, <-errc, context.Canceled)
+	require.Equal(t, 1, clock.Pending())
+}
+
+func TestNewStrategy_SeededByClock(t *testing.T) {
+	opts := RetryOptions{
+		InitialDelay: 100 * time.Millisecond,
+		MaxJitter:    time.Second,
+		Clock:        NewFakeClock(time.Unix(42, 0)),
+	}
+
+	for _, name := range []string{BackoffExponential, BackoffDecorrelatedJitter, BackoffFullJitter} {
+		t.Run(name, func(t *testing.T) {
+			opts.Backoff = name
+
+			first, err := NewStrategy(opts)
+			require.NoError(t, err)
+			second, err := NewStrategy(opts)
+			require.NoError(t, err)
+
+			require.Equal(t,
+				intervals(first, opts.InitialDelay, time.Minute, 8),
+				intervals(second, opts.InitialDelay, time.Minute, 8))
+		})
+	}
+}
+
+func succeedAt(n int) RetryFunc {
+	attempts := 0
+	return func() (bool, error) {
+		attempts++
+		return attempts >= n, nil
+	}
+}
@@ -3,6 +3,8 @@ package retry
 import (
 	"context"
 	"errors"
+	"fmt"
+	"net/http"
 	"time"
 
 	"github.com/lucasepe/x/env"
@@ -12,6 +14,44 @@ var (
 	ErrExhausted = errors.New("function never succeeded in Retry")
 )
 
+// DelayHint can be returned by a RetryFunc to ask the Retrier to wait
+// the given delay before the next attempt, instead of the interval
+// computed by the Strategy. It is not treated as an error.
+type DelayHint struct {
+	Delay time.Duration
+}
+
+func (dh *DelayHint) Error() string {
+	return fmt.Sprintf("retry after %s", dh.Delay)
+}
+
+// After returns a DelayHint for the given delay.
+func After(d time.Duration) error {
+	return &DelayHint{Delay: d}
+}
+
+// StatusError records the HTTP status code of a retried response.
+// It is wrapped by ErrExhausted when every attempt got a retryable status.
+type StatusError struct {
+	StatusCode int
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("last response status: %d %s", e.StatusCode, http.StatusText(e.StatusCode))
+}
+
+// FailError is returned when the fail-if expression evaluates to true
+// on a response, stopping the retry loop early.
+type FailError struct {
+	Expr string
+	// Body is the offending response body.
+	Body []byte
+}
+
+func (e *FailError) Error() string {
+	return fmt.Sprintf("fail condition met: %s", e.Expr)
+}
+
 type Retrier interface {
 	Retry(context.Context, Strategy, RetryFunc) error
 }
@@ -23,6 +63,18 @@ type RetryOptions struct {
 	MaxDelay     time.Duration
 	MaxAttempts  int
 	MaxJitter    time.Duration
+	// Timeout is the deadline for the whole wait, retries included.
+	// It is enforced by the caller through the request context.
+	Timeout time.Duration
+	// RequestTimeout bounds each single attempt.
+	RequestTimeout time.Duration
+	// Backoff names the Strategy (see NewStrategy).
+	Backoff string
+	// Multiplier is the growth factor of the Backoff strategy.
+	Multiplier float64
+	// Clock waits the delays between the attempts and seeds the
+	// jittered strategies; RealClock when nil.
+	Clock Clock
 }
 
 func OptionsFromEnv() (res RetryOptions) {
@@ -30,6 +82,10 @@ func OptionsFromEnv() (res RetryOptions) {
 	res.MaxDelay = env.Duration(maxDelayEnv, 20*time.Second)
 	res.MaxAttempts = env.Int(maxAttemptsEnv, 15)
 	res.MaxJitter = env.Duration(maxJitterEnv, 1*time.Second)
+	res.Timeout = env.Duration(timeoutEnv, 0)
+	res.RequestTimeout = env.Duration(requestTimeoutEnv, 0)
+	res.Backoff = env.Str(backoffEnv, BackoffExponential)
+	res.Multiplier = env.Float64(backoffMultiplierEnv, 0)
 	return res
 }
 
@@ -38,6 +94,7 @@ func NewRetrier(opts RetryOptions) Retrier {
 		initialDelay: opts.InitialDelay,
 		maxDelay:     opts.MaxDelay,
 		maxAttempts:  opts.MaxAttempts,
+		clock:        clockOrReal(opts.Clock),
 	}
 
 	// Sanity check: initialDelay should not be greater than maxDelay
@@ -57,12 +114,19 @@ const (
 	maxDelayEnv     = "MAX_DELAY"
 	maxAttemptsEnv  = "MAX_ATTEMPTS"
 	maxJitterEnv    = "MAX_JITTER"
+
+	timeoutEnv        = "TIMEOUT"
+	requestTimeoutEnv = "REQUEST_TIMEOUT"
+
+	backoffEnv           = "BACKOFF"
+	backoffMultiplierEnv = "BACKOFF_MULTIPLIER"
 )
 
 type retrierImpl struct {
 	initialDelay time.Duration
 	maxDelay     time.Duration
 	maxAttempts  int
+	clock        Clock
 }
 
 func (ri *retrierImpl) Retry(ctx context.Context, strategy Strategy, fn RetryFunc) error {
@@ -78,21 +142,27 @@ func (ri *retrierImpl) Retry(ctx context.Context, strategy Strategy, fn RetryFun
 	interval := ri.initialDelay
 
 	for i := 0; !done && i < ri.maxAttempts; i++ {
-		//log.Printf("retry: attempt %d of %d\n", i+1, ri.attempts)
-
 		done, err = fn()
 
 		if ctx.Err() != nil {
 			return ctx.Err()
 		}
 
+		delay := interval
+
+		var hint *DelayHint
+		if errors.As(err, &hint) {
+			err = nil
+			delay = min(max(hint.Delay, 0), ri.maxDelay)
+		}
+
 		if err != nil {
 			return err
 		}
 
 		if !done && i+1 < ri.maxAttempts { // do not sleep after last attempt
 			select {
-			case <-time.After(interval):
+			case <-ri.clock.After(delay):
 				// continue
 			case <-ctx.Done():
 				return ctx.Err()
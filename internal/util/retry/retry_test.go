@@ -14,11 +14,13 @@ var (
 )
 
 func TestRetrierRetryContextDeadlineFail(t *testing.T) {
+	clock := retry.NewFakeClock(time.Unix(0, 0))
 	r := retry.NewRetrier(
 		retry.RetryOptions{
 			InitialDelay: 125 * time.Millisecond,
 			MaxDelay:     250 * time.Millisecond,
 			MaxAttempts:  2,
+			Clock:        clock,
 		},
 	)
 
@@ -40,11 +42,13 @@ func TestRetrierRetryContextDeadlineFail(t *testing.T) {
 }
 
 func TestRetrierRetry(t *testing.T) {
+	clock := retry.NewFakeClock(time.Unix(0, 0))
 	r := retry.NewRetrier(
 		retry.RetryOptions{
 			InitialDelay: 125 * time.Millisecond,
 			MaxDelay:     250 * time.Millisecond,
 			MaxAttempts:  2,
+			Clock:        clock,
 		},
 	)
 	err := r.Retry(context.Background(), retry.Exp(), func() (bool, error) {
@@ -57,11 +61,13 @@ func TestRetrierRetry(t *testing.T) {
 }
 
 func TestRetrierRetryTriggerError(t *testing.T) {
+	clock := retry.NewFakeClock(time.Unix(0, 0))
 	r := retry.NewRetrier(
 		retry.RetryOptions{
 			InitialDelay: 125 * time.Millisecond,
 			MaxDelay:     250 * time.Millisecond,
 			MaxAttempts:  2,
+			Clock:        clock,
 		},
 	)
 	err := r.Retry(context.Background(), retry.Exp(), func() (bool, error) {
@@ -78,24 +84,87 @@ func TestRetrierRetryTriggerError(t *testing.T) {
 }
 
 func TestRetrierRetryFail(t *testing.T) {
+	clock := retry.NewFakeClock(time.Unix(0, 0))
 	r := retry.NewRetrier(
 		retry.RetryOptions{
 			InitialDelay: 125 * time.Millisecond,
 			MaxDelay:     250 * time.Millisecond,
 			MaxAttempts:  2,
+			Clock:        clock,
 		},
 	)
 
-	err := r.Retry(context.Background(), retry.Exp(), func() (bool, error) {
-		return false, nil
+	var err error
+	clock.Run(func() {
+		err = r.Retry(context.Background(), retry.Exp(), func() (bool, error) {
+			return false, nil
+		})
 	})
 
 	if err == nil {
 		t.Fatal("unexpected nil error")
 	}
 
+	if got := clock.Waits(); len(got) != 1 || got[0] != 125*time.Millisecond {
+		t.Fatalf("expected to wait 125ms once, got %v", got)
+	}
+
 	expectedErrorMessage := "function never succeeded in Retry"
 	if err.Error() != expectedErrorMessage {
 		t.Fatal(err)
 	}
 }
+
+func TestRetrierRetryDelayHint(t *testing.T) {
+	tests := []struct {
+		name string
+		hint time.Duration
+	}{
+		{name: "hint shorter than the interval", hint: time.Millisecond},
+		{name: "hint clamped to max delay", hint: time.Hour},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			clock := retry.NewFakeClock(time.Unix(0, 0))
+			r := retry.NewRetrier(
+				retry.RetryOptions{
+					InitialDelay: 10 * time.Millisecond,
+					MaxDelay:     20 * time.Millisecond,
+					MaxAttempts:  3,
+					Clock:        clock,
+				},
+			)
+
+			attempts := 0
+			errc := make(chan error, 1)
+			go func() {
+				errc <- r.Retry(context.Background(), retry.Exp(), func() (bool, error) {
+					attempts++
+					if attempts < 3 {
+						return false, retry.After(tc.hint)
+					}
+					return true, nil
+				})
+			}()
+
+			for range 2 {
+				<-clock.Started()
+				clock.AdvanceToNext()
+			}
+
+			if err := <-errc; err != nil {
+				t.Fatalf("unexpected error (%v)", err)
+			}
+
+			if attempts != 3 {
+				t.Fatalf("expected 3 attempts, got %d", attempts)
+			}
+
+			want := min(tc.hint, 20*time.Millisecond)
+			if got := clock.Waits(); len(got) != 2 || got[0] != want || got[1] != want {
+				t.Fatalf("expected to wait %s twice, got %v", want, got)
+			}
+		})
+	}
+}
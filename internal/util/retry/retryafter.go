@@ -0,0 +1,60 @@
+package retry
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// delayFromHeaders returns the back-off suggested by the server through
+// the Retry-After header (delay-seconds or HTTP-date) or, when the rate
+// limit has been hit, through the X-RateLimit-Reset / RateLimit-Reset headers.
+//
+// X-RateLimit-Reset is interpreted as a unix epoch (GitHub style) when it
+// looks like one, as delta seconds otherwise.
+func delayFromHeaders(h http.Header, now time.Time) (time.Duration, bool) {
+	if v := strings.TrimSpace(h.Get("Retry-After")); v != "" {
+		if secs, err := strconv.Atoi(v); err == nil {
+			return max(time.Duration(secs)*time.Second, 0), true
+		}
+
+		if at, err := http.ParseTime(v); err == nil {
+			return max(at.Sub(now), 0), true
+		}
+	}
+
+	remaining := h.Get("X-RateLimit-Remaining")
+	if remaining == "" {
+		remaining = h.Get("RateLimit-Remaining")
+	}
+	if strings.TrimSpace(remaining) != "0" {
+		return 0, false
+	}
+
+	if v := strings.TrimSpace(h.Get("X-RateLimit-Reset")); v != "" {
+		if n, err := strconv.ParseInt(v, 10, 64); err == nil {
+			if n > epochThreshold {
+				return max(time.Unix(n, 0).Sub(now), 0), true
+			}
+			return max(time.Duration(n)*time.Second, 0), true
+		}
+	}
+
+	if v := strings.TrimSpace(h.Get("RateLimit-Reset")); v != "" {
+		if secs, err := strconv.Atoi(v); err == nil {
+			return max(time.Duration(secs)*time.Second, 0), true
+		}
+	}
+
+	return 0, false
+}
+
+// epochThreshold separates delta seconds from unix timestamps (2001-09-09).
+const epochThreshold = 1_000_000_000
+
+// RetryAfter returns the delay the server asks to wait before the
+// next request, if any (see delayFromHeaders); dates are relative to now.
+func RetryAfter(h http.Header, now time.Time) (time.Duration, bool) {
+	return delayFromHeaders(h, now)
+}
@@ -0,0 +1,83 @@
+package retry
+
+import (
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestDelayFromHeaders(t *testing.T) {
+	now := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name    string
+		header  http.Header
+		want    time.Duration
+		wantHit bool
+	}{
+		{
+			name:    "retry-after seconds",
+			header:  http.Header{"Retry-After": []string{"7"}},
+			want:    7 * time.Second,
+			wantHit: true,
+		},
+		{
+			name:    "retry-after http date",
+			header:  http.Header{"Retry-After": []string{now.Add(90 * time.Second).Format(http.TimeFormat)}},
+			want:    90 * time.Second,
+			wantHit: true,
+		},
+		{
+			name:    "retry-after date in the past",
+			header:  http.Header{"Retry-After": []string{now.Add(-time.Minute).Format(http.TimeFormat)}},
+			want:    0,
+			wantHit: true,
+		},
+		{
+			name: "x-ratelimit-reset epoch",
+			header: http.Header{
+				"X-Ratelimit-Remaining": []string{"0"},
+				"X-Ratelimit-Reset":     []string{strconv.FormatInt(now.Add(30*time.Second).Unix(), 10)},
+			},
+			want:    30 * time.Second,
+			wantHit: true,
+		},
+		{
+			name: "ratelimit-reset delta seconds",
+			header: http.Header{
+				"Ratelimit-Remaining": []string{"0"},
+				"Ratelimit-Reset":     []string{"12"},
+			},
+			want:    12 * time.Second,
+			wantHit: true,
+		},
+		{
+			name: "rate limit not exhausted",
+			header: http.Header{
+				"X-Ratelimit-Remaining": []string{"42"},
+				"X-Ratelimit-Reset":     []string{"12"},
+			},
+		},
+		{
+			name:   "garbage retry-after",
+			header: http.Header{"Retry-After": []string{"soon"}},
+		},
+		{
+			name:   "no hints",
+			header: http.Header{},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, ok := delayFromHeaders(tc.header, now)
+			if ok != tc.wantHit {
+				t.Fatalf("hit = %v, want %v", ok, tc.wantHit)
+			}
+			if got != tc.want {
+				t.Errorf("delay = %s, want %s", got, tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,107 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+// RetryOn classifies HTTP responses and transport errors as retryable
+// or fatal. The zero value retries on nothing.
+type RetryOn struct {
+	statuses     map[int]bool
+	classes      map[int]bool
+	connectError bool
+	timeout      bool
+}
+
+// ParseRetryOn parses a comma separated list of retryable conditions.
+//
+// Supported conditions are:
+//   - an HTTP status code (e.g. 429, 503)
+//   - an HTTP status class (e.g. 5xx, 4xx)
+//   - connect-error: connection refused, reset or DNS failures
+//   - timeout: the request (or a single attempt) timed out
+//
+// Example:
+//
+//	ro, err := ParseRetryOn("5xx,429,connect-error,timeout")
+func ParseRetryOn(s string) (RetryOn, error) {
+	res := RetryOn{
+		statuses: map[int]bool{},
+		classes:  map[int]bool{},
+	}
+
+	for _, el := range strings.Split(s, ",") {
+		el = strings.ToLower(strings.TrimSpace(el))
+
+		switch {
+		case el == "":
+			continue
+		case el == "connect-error":
+			res.connectError = true
+		case el == "timeout":
+			res.timeout = true
+		case len(el) == 3 && strings.HasSuffix(el, "xx"):
+			class, err := strconv.Atoi(el[:1])
+			if err != nil || class < 1 || class > 5 {
+				return RetryOn{}, fmt.Errorf("invalid retry-on status class: %q", el)
+			}
+			res.classes[class] = true
+		default:
+			code, err := strconv.Atoi(el)
+			if err != nil || code < 100 || code > 599 {
+				return RetryOn{}, fmt.Errorf("invalid retry-on condition: %q", el)
+			}
+			res.statuses[code] = true
+		}
+	}
+
+	return res, nil
+}
+
+// RetryStatus reports whether a response with the given status code should be retried.
+func (ro RetryOn) RetryStatus(code int) bool {
+	return ro.statuses[code] || ro.classes[code/100]
+}
+
+// RetryError reports whether a transport error should be retried.
+// Any other error is considered fatal.
+func (ro RetryOn) RetryError(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	if ro.timeout && isTimeout(err) {
+		return true
+	}
+
+	return ro.connectError && isConnectError(err)
+}
+
+func isTimeout(err error) bool {
+	if errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
+
+func isConnectError(err error) bool {
+	if errors.Is(err, syscall.ECONNREFUSED) || errors.Is(err, syscall.ECONNRESET) {
+		return true
+	}
+
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) {
+		return true
+	}
+
+	var opErr *net.OpError
+	return errors.As(err, &opErr) && opErr.Op == "dial"
+}
@@ -0,0 +1,72 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"net"
+	"syscall"
+	"testing"
+)
+
+func TestParseRetryOn(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		wantErr bool
+	}{
+		{name: "empty", input: ""},
+		{name: "all conditions", input: "5xx, 429 ,connect-error,timeout"},
+		{name: "invalid class", input: "9xx", wantErr: true},
+		{name: "invalid status", input: "42", wantErr: true},
+		{name: "unknown condition", input: "sometimes", wantErr: true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := ParseRetryOn(tc.input)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("unexpected error status: got err=%v, wantErr=%v", err, tc.wantErr)
+			}
+		})
+	}
+}
+
+func TestRetryOnClassification(t *testing.T) {
+	ro, err := ParseRetryOn("5xx,429,connect-error,timeout")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	statuses := map[int]bool{
+		200: false,
+		404: false,
+		429: true,
+		500: true,
+		503: true,
+	}
+	for code, want := range statuses {
+		if got := ro.RetryStatus(code); got != want {
+			t.Errorf("RetryStatus(%d) = %v, want %v", code, got, want)
+		}
+	}
+
+	errs := []struct {
+		err  error
+		want bool
+	}{
+		{&net.OpError{Op: "dial", Err: syscall.ECONNREFUSED}, true},
+		{&net.DNSError{Err: "no such host", Name: "nope.invalid"}, true},
+		{context.DeadlineExceeded, true},
+		{errors.New("x509: certificate signed by unknown authority"), false},
+		{nil, false},
+	}
+	for _, tc := range errs {
+		if got := ro.RetryError(tc.err); got != tc.want {
+			t.Errorf("RetryError(%v) = %v, want %v", tc.err, got, tc.want)
+		}
+	}
+
+	if (RetryOn{}).RetryError(context.DeadlineExceeded) {
+		t.Error("zero value RetryOn must not retry")
+	}
+}
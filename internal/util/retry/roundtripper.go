@@ -3,63 +3,384 @@ package retry
 import (
 	"bytes"
 	"context"
+	"errors"
+	"fmt"
 	"io"
+	"log/slog"
 	"net/http"
 	"strings"
+	"time"
 
+	ioutil "github.com/lucasepe/resto/internal/util/io"
 	"github.com/lucasepe/resto/internal/util/jq"
 )
 
-func NewRoundTripperWithEval(next http.RoundTripper, expr string, strategy Strategy, retrier Retrier) *retryRoundTripper {
+type RoundTripperOptions struct {
+	// Until is the JQ expression evaluated on JSON responses;
+	// the request is retried until it evaluates to true.
+	Until string
+	// FailIf is the JQ expression evaluated on JSON responses;
+	// when it evaluates to true the retry loop stops with a *FailError.
+	FailIf string
+	// RetryOn classifies the responses and transport errors
+	// that should be retried instead of failing immediately.
+	RetryOn RetryOn
+	// RequestTimeout bounds each single attempt; zero means no limit.
+	// For streaming responses it only bounds the wait for the headers.
+	RequestTimeout time.Duration
+	// Stream hands successful responses over without reading their body,
+	// so that long-lived responses (watches, event streams) can be consumed
+	// incrementally; Until and FailIf are left to the consumer.
+	// Responses with a streaming content type are always handled this way.
+	Stream bool
+	// Logger, if set, receives one record per attempt.
+	Logger *slog.Logger
+	// Clock times the attempts and bounds them (see RequestTimeout);
+	// RealClock when nil.
+	Clock    Clock
+	Strategy Strategy
+	Retrier  Retrier
+}
+
+func NewRoundTripper(next http.RoundTripper, opts RoundTripperOptions) *retryRoundTripper {
 	return &retryRoundTripper{
-		retrier:    retrier,
-		strategy:   strategy,
+		retrier:    opts.Retrier,
+		strategy:   opts.Strategy,
 		next:       next,
-		expression: expr,
+		expression: opts.Until,
+		failIf:     opts.FailIf,
+		retryOn:    opts.RetryOn,
+		timeout:    opts.RequestTimeout,
+		stream:     opts.Stream,
+		logger:     opts.Logger,
+		clock:      clockOrReal(opts.Clock),
 	}
 }
 
+func NewRoundTripperWithEval(next http.RoundTripper, expr string, strategy Strategy, retrier Retrier) *retryRoundTripper {
+	return NewRoundTripper(next, RoundTripperOptions{
+		Until:    expr,
+		Strategy: strategy,
+		Retrier:  retrier,
+	})
+}
+
 type retryRoundTripper struct {
 	retrier    Retrier
 	strategy   Strategy
 	next       http.RoundTripper
 	expression string
+	failIf     string
+	retryOn    RetryOn
+	timeout    time.Duration
+	stream     bool
+	logger     *slog.Logger
+	clock      Clock
 }
 
 func (rt *retryRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
-	var resp *http.Response
+	var (
+		st      roundTripState
+		lastEnd time.Time
+	)
+
+	err := rt.retrier.Retry(req.Context(), rt.strategy, func() (bool, error) {
+		rec := attemptRecord{attempt: st.count + 1, start: rt.clock.Now(), bytes: -1}
+		if !lastEnd.IsZero() {
+			rec.delay = rec.start.Sub(lastEnd)
+		}
+
+		done, err := rt.attempt(req, &st, &rec)
+
+		lastEnd = rt.clock.Now()
+		rec.latency = lastEnd.Sub(rec.start)
+		rt.logAttempt(req, rec, err, st.lastErr)
+
+		return done, err
+	})
+
+	if errors.Is(err, ErrExhausted) && st.lastErr != nil {
+		err = fmt.Errorf("%w: %w", ErrExhausted, st.lastErr)
+	}
+
+	if err != nil {
+		// the errors carry what the caller needs (e.g. FailError.Body);
+		// net/http ignores a response returned along with an error
+		if st.resp != nil && st.resp.Body != nil {
+			io.Copy(io.Discard, st.resp.Body)
+			st.resp.Body.Close()
+		}
+		return nil, err
+	}
+
+	return st.resp, nil
+}
+
+// roundTripState is shared by the attempts of a RoundTrip.
+type roundTripState struct {
+	resp    *http.Response
+	lastErr error
+	count   int
+}
+
+// attemptRecord describes a single attempt for the logs.
+type attemptRecord struct {
+	attempt int
+	start   time.Time
+	delay   time.Duration
+	latency time.Duration
+	status  int
+	bytes   int
+	until   *bool
+}
 
-	err := rt.retrier.Retry(context.Background(), rt.strategy, func() (bool, error) {
-		var err error
-		resp, err = rt.next.RoundTrip(req)
+// attempt sends the request once and tells whether the response is final.
+func (rt *retryRoundTripper) attempt(req *http.Request, st *roundTripState, rec *attemptRecord) (bool, error) {
+	st.count++
+	attempt, timer := rt.startAttempt(req, st.count)
+
+	// the previous attempt consumed the body
+	if st.count > 1 && req.GetBody != nil && req.Body != nil && req.Body != http.NoBody {
+		body, err := req.GetBody()
 		if err != nil {
+			timer.stop()
+			return false, fmt.Errorf("unable to rewind the request body: %w", err)
+		}
+		attempt.Body = body
+	}
+
+	var err error
+	st.resp, err = rt.next.RoundTrip(attempt)
+	if err != nil {
+		timer.stop()
+		err = timer.wrap(err)
+		if rt.retryOn.RetryError(err) {
+			st.lastErr = err
+			return false, nil
+		}
+		return false, err
+	}
+	st.lastErr = nil
+
+	resp := st.resp
+	rec.status = resp.StatusCode
+
+	if resp.Body == nil {
+		timer.stop()
+		return false, nil
+	}
+
+	if rt.isStream(resp) && !rt.retryOn.RetryStatus(resp.StatusCode) {
+		if !timer.detach(resp) {
+			resp.Body.Close()
+			err = timer.wrap(context.Canceled)
+			if rt.retryOn.RetryError(err) {
+				st.lastErr = err
+				return false, nil
+			}
 			return false, err
 		}
+		return true, nil
+	}
 
-		if resp.Body == nil {
+	bin, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	timer.stop()
+	if err != nil {
+		err = timer.wrap(err)
+		if rt.retryOn.RetryError(err) {
+			st.lastErr = err
 			return false, nil
 		}
+		return false, err
+	}
+	resp.Body = io.NopCloser(bytes.NewBuffer(bin)) // ripristina il body
+	rec.bytes = len(bin)
+
+	if rt.retryOn.RetryStatus(resp.StatusCode) {
+		st.lastErr = &StatusError{StatusCode: resp.StatusCode}
+		if delay, ok := delayFromHeaders(resp.Header, rt.clock.Now()); ok {
+			return false, After(delay)
+		}
+		return false, nil
+	}
+
+	// an error response is final: the conditions apply to successful ones,
+	// unless the server asks to come back later while the condition is awaited
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		if rt.expression != "" && !conditionDisabled(req.Context()) && isBusy(resp.StatusCode) {
+			if delay, ok := delayFromHeaders(resp.Header, rt.clock.Now()); ok {
+				st.lastErr = &StatusError{StatusCode: resp.StatusCode}
+				return false, After(delay)
+			}
+		}
+		return true, nil
+	}
+
+	if conditionDisabled(req.Context()) {
+		return true, nil
+	}
+
+	contentType := strings.ToLower(resp.Header.Get("Content-Type"))
+
+	isJSON := strings.Contains(contentType, "application/json")
+
+	if rt.failIf != "" && isJSON {
+		failed, err := jq.EvalBoolExpr(bin, rt.failIf)
+		if err != nil {
+			return false, err
+		}
+		if failed {
+			return false, &FailError{Expr: rt.failIf, Body: bin}
+		}
+	}
 
-		bin, err := io.ReadAll(resp.Body)
+	switch {
+	case rt.expression != "" && isJSON:
+		ok, err := jq.EvalBoolExpr(bin, rt.expression)
 		if err != nil {
 			return false, err
 		}
-		resp.Body.Close()
-		resp.Body = io.NopCloser(bytes.NewBuffer(bin)) // ripristina il body
+		rec.until = &ok
+		if !ok {
+			if delay, found := delayFromHeaders(resp.Header, rt.clock.Now()); found {
+				return false, After(delay)
+			}
+		}
+		return ok, nil
 
-		contentType := strings.ToLower(resp.Header.Get("Content-Type"))
+	default:
+		// Non gestito: consideriamo valido
+		return true, nil
+	}
+}
 
-		isJSON := strings.Contains(contentType, "application/json")
+// isBusy reports whether the status code tells the client to retry later.
+func isBusy(code int) bool {
+	return code == http.StatusTooManyRequests || code == http.StatusServiceUnavailable
+}
 
-		switch {
-		case rt.expression != "" && isJSON:
-			return jq.EvalBoolExpr(bin, rt.expression)
+// logAttempt emits one record for the attempt: at Info level when it
+// went through, at Warn level when it failed (err) or must be retried
+// (lastErr).
+func (rt *retryRoundTripper) logAttempt(req *http.Request, rec attemptRecord, err, lastErr error) {
+	if rt.logger == nil {
+		return
+	}
 
-		default:
-			// Non gestito: consideriamo valido
-			return true, nil
-		}
-	})
+	attrs := []slog.Attr{
+		slog.Int("attempt", rec.attempt),
+		slog.Float64("delay_ms", millis(rec.delay)),
+		slog.String("method", req.Method),
+		slog.String("url", req.URL.String()),
+	}
+	if rec.status > 0 {
+		attrs = append(attrs, slog.Int("status", rec.status))
+	}
+	attrs = append(attrs, slog.Float64("latency_ms", millis(rec.latency)))
+	if rec.bytes >= 0 {
+		attrs = append(attrs, slog.Int("bytes", rec.bytes))
+	}
+	if rec.until != nil {
+		attrs = append(attrs, slog.Bool("until", *rec.until))
+	}
+
+	var hint *DelayHint
+	if errors.As(err, &hint) {
+		attrs = append(attrs, slog.Float64("retry_after_ms", millis(hint.Delay)))
+		err = nil
+	}
+	if err == nil {
+		err = lastErr
+	}
+
+	level := slog.LevelInfo
+	if err != nil {
+		level = slog.LevelWarn
+		attrs = append(attrs, slog.String("error", err.Error()))
+	}
+
+	rt.logger.LogAttrs(req.Context(), level, "attempt", attrs...)
+}
+
+func millis(d time.Duration) float64 {
+	return float64(d.Microseconds()) / 1000
+}
+
+// isStream reports whether the response body must be handed over
+// without being read.
+func (rt *retryRoundTripper) isStream(resp *http.Response) bool {
+	return rt.stream || ioutil.IsStreamingContentType(resp.Header.Get("Content-Type"))
+}
+
+// startAttempt returns the request for the n-th attempt, bounded
+// by the request timeout if any.
+//
+// Unlike context.WithTimeout, the timer can be stopped once the
+// headers of a streaming response have been received, leaving
+// the body readable for as long as the caller needs.
+func (rt *retryRoundTripper) startAttempt(req *http.Request, n int) (*http.Request, *attemptTimer) {
+	ctx := WithAttempt(req.Context(), n)
+	if rt.timeout <= 0 {
+		return req.WithContext(ctx), &attemptTimer{}
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	return req.WithContext(ctx), &attemptTimer{
+		timeout: rt.timeout,
+		cancel:  cancel,
+		timer:   rt.clock.AfterFunc(rt.timeout, cancel),
+	}
+}
+
+type attemptTimer struct {
+	timeout time.Duration
+	cancel  context.CancelFunc
+	timer   Timer
+	fired   bool
+}
+
+// stop releases the attempt context.
+func (at *attemptTimer) stop() {
+	if at.timer == nil {
+		return
+	}
+	at.fired = !at.timer.Stop() || at.fired
+	at.cancel()
+}
+
+// detach stops the timer and releases the attempt context only when
+// the response body is closed. It returns false if the timeout already
+// expired.
+func (at *attemptTimer) detach(resp *http.Response) bool {
+	if at.timer == nil {
+		return true
+	}
+	if !at.timer.Stop() {
+		at.fired = true
+		at.cancel()
+		return false
+	}
+	resp.Body = &cancelOnClose{ReadCloser: resp.Body, cancel: at.cancel}
+	return true
+}
+
+// wrap reports the cancellation caused by the expired timer
+// as a deadline exceeded error.
+func (at *attemptTimer) wrap(err error) error {
+	if !at.fired {
+		return err
+	}
+	return fmt.Errorf("request timeout of %s exceeded: %w", at.timeout, context.DeadlineExceeded)
+}
+
+type cancelOnClose struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
 
-	return resp, err
+func (c *cancelOnClose) Close() error {
+	err := c.ReadCloser.Close()
+	c.cancel()
+	return err
 }
@@ -4,10 +4,16 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"fmt"
 	"io"
+	"log/slog"
+	"net"
+	"sync/atomic"
+	"syscall"
 	"time"
 
 	"net/http"
+	"net/http/httptest"
 	"testing"
 
 	"github.com/stretchr/testify/require"
@@ -74,3 +80,548 @@ func (m *mockTextTransport) RoundTrip(req *http.Request) (*http.Response, error)
 		Header:     http.Header{"Content-Type": []string{"text/plain"}},
 	}, nil
 }
+
+type mockStatusTransport struct {
+	callCount int
+	failures  int
+	status    int
+	json      bool
+	err       error
+}
+
+func (m *mockStatusTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	m.callCount++
+
+	if m.callCount <= m.failures {
+		if m.err != nil {
+			return nil, m.err
+		}
+		if m.json {
+			return &http.Response{
+				StatusCode: m.status,
+				Body:       io.NopCloser(bytes.NewBufferString(`{"message": "not found"}`)),
+				Header:     http.Header{"Content-Type": []string{"application/json"}},
+			}, nil
+		}
+		return &http.Response{
+			StatusCode: m.status,
+			Body:       io.NopCloser(bytes.NewBufferString("unavailable")),
+			Header:     http.Header{"Content-Type": []string{"text/plain"}},
+		}, nil
+	}
+
+	return &http.Response{
+		StatusCode: 200,
+		Body:       io.NopCloser(bytes.NewBufferString(`{"ready": true}`)),
+		Header:     http.Header{"Content-Type": []string{"application/json"}},
+	}, nil
+}
+
+func TestRetryRoundTripper_RetryOn(t *testing.T) {
+	connRefused := &net.OpError{Op: "dial", Net: "tcp", Err: syscall.ECONNREFUSED}
+
+	tests := []struct {
+		name      string
+		retryOn   string
+		mock      *mockStatusTransport
+		wantCalls int
+		wantCode  int
+		wantErr   error
+	}{
+		{
+			name:      "retry on 5xx",
+			retryOn:   "5xx",
+			mock:      &mockStatusTransport{failures: 2, status: 503},
+			wantCalls: 3,
+		},
+		{
+			name:      "retry on explicit status",
+			retryOn:   "429",
+			mock:      &mockStatusTransport{failures: 1, status: 429},
+			wantCalls: 2,
+		},
+		{
+			name:      "status not in policy is returned as is",
+			retryOn:   "429",
+			mock:      &mockStatusTransport{failures: 1, status: 503},
+			wantCalls: 1,
+		},
+		{
+			name:      "JSON error response is final",
+			mock:      &mockStatusTransport{failures: 2, status: 404, json: true},
+			wantCalls: 1,
+			wantCode:  404,
+		},
+		{
+			name:      "retry on connect error",
+			retryOn:   "connect-error",
+			mock:      &mockStatusTransport{failures: 2, err: connRefused},
+			wantCalls: 3,
+		},
+		{
+			name:      "connect error is fatal without policy",
+			mock:      &mockStatusTransport{failures: 2, err: connRefused},
+			wantCalls: 1,
+			wantErr:   syscall.ECONNREFUSED,
+		},
+		{
+			name:      "exhausted keeps the last cause",
+			retryOn:   "connect-error",
+			mock:      &mockStatusTransport{failures: 10, err: connRefused},
+			wantCalls: 5,
+			wantErr:   ErrExhausted,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			retryOn, err := ParseRetryOn(tc.retryOn)
+			require.NoError(t, err)
+
+			rt := NewRoundTripper(tc.mock, RoundTripperOptions{
+				Until:    ".ready",
+				RetryOn:  retryOn,
+				Strategy: Exp(),
+				Retrier: NewRetrier(RetryOptions{
+					InitialDelay: time.Millisecond,
+					MaxDelay:     5 * time.Millisecond,
+					MaxAttempts:  5,
+				}),
+			})
+
+			req, _ := http.NewRequestWithContext(context.Background(), http.MethodGet, "http://example.com", nil)
+
+			resp, err := rt.RoundTrip(req)
+			if tc.wantErr != nil {
+				require.ErrorIs(t, err, tc.wantErr)
+				require.Nil(t, resp)
+			} else {
+				require.NoError(t, err)
+			}
+			require.Equal(t, tc.wantCalls, tc.mock.callCount)
+			if tc.wantCode != 0 {
+				require.Equal(t, tc.wantCode, resp.StatusCode)
+			}
+		})
+	}
+}
+
+type mockSlowTransport struct {
+	callCount int
+	slowCalls int
+}
+
+func (m *mockSlowTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	m.callCount++
+
+	if m.callCount <= m.slowCalls {
+		<-req.Context().Done()
+		return nil, req.Context().Err()
+	}
+
+	return &http.Response{
+		StatusCode: 200,
+		Body:       io.NopCloser(bytes.NewBufferString(`{"ready": true}`)),
+		Header:     http.Header{"Content-Type": []string{"application/json"}},
+	}, nil
+}
+
+func TestRetryRoundTripper_Timeouts(t *testing.T) {
+	retryOn, err := ParseRetryOn("timeout")
+	require.NoError(t, err)
+
+	newRoundTripper := func(next http.RoundTripper) http.RoundTripper {
+		return NewRoundTripper(next, RoundTripperOptions{
+			Until:          ".ready",
+			RetryOn:        retryOn,
+			RequestTimeout: 20 * time.Millisecond,
+			Strategy:       Exp(),
+			Retrier: NewRetrier(RetryOptions{
+				InitialDelay: time.Millisecond,
+				MaxDelay:     5 * time.Millisecond,
+				MaxAttempts:  100,
+			}),
+		})
+	}
+
+	t.Run("slow attempt is retried", func(t *testing.T) {
+		mock := &mockSlowTransport{slowCalls: 2}
+
+		req, _ := http.NewRequestWithContext(context.Background(), http.MethodGet, "http://example.com", nil)
+
+		_, err := newRoundTripper(mock).RoundTrip(req)
+		require.NoError(t, err)
+		require.Equal(t, 3, mock.callCount)
+	})
+
+	t.Run("overall deadline stops the loop", func(t *testing.T) {
+		mock := &mockSlowTransport{slowCalls: 1000}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+		defer cancel()
+
+		req, _ := http.NewRequestWithContext(ctx, http.MethodGet, "http://example.com", nil)
+
+		_, err := newRoundTripper(mock).RoundTrip(req)
+		require.ErrorIs(t, err, context.DeadlineExceeded)
+		require.Less(t, mock.callCount, 100)
+	})
+}
+
+type mockPhaseTransport struct {
+	callCount int
+	phases    []string
+}
+
+func (m *mockPhaseTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	phase := m.phases[min(m.callCount, len(m.phases)-1)]
+	m.callCount++
+
+	return &http.Response{
+		StatusCode: 200,
+		Body:       io.NopCloser(bytes.NewBufferString(`{"phase": "` + phase + `"}`)),
+		Header:     http.Header{"Content-Type": []string{"application/json"}},
+	}, nil
+}
+
+func TestRetryRoundTripper_FailIf(t *testing.T) {
+	mock := &mockPhaseTransport{phases: []string{"Pending", "Failed", "Running"}}
+
+	rt := NewRoundTripper(mock, RoundTripperOptions{
+		Until:    `.phase == "Running"`,
+		FailIf:   `.phase == "Failed"`,
+		Strategy: Exp(),
+		Retrier: NewRetrier(RetryOptions{
+			InitialDelay: time.Millisecond,
+			MaxDelay:     5 * time.Millisecond,
+			MaxAttempts:  5,
+		}),
+	})
+
+	req, _ := http.NewRequestWithContext(context.Background(), http.MethodGet, "http://example.com", nil)
+
+	resp, err := rt.RoundTrip(req)
+	require.Nil(t, resp)
+
+	var failErr *FailError
+	require.ErrorAs(t, err, &failErr)
+	require.JSONEq(t, `{"phase": "Failed"}`, string(failErr.Body))
+	require.Equal(t, 2, mock.callCount)
+}
+
+type mockStreamTransport struct {
+	callCount int
+	ctx       context.Context
+	body      *io.PipeReader
+}
+
+func (m *mockStreamTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	m.callCount++
+	m.ctx = req.Context()
+
+	return &http.Response{
+		StatusCode: 200,
+		Body:       m.body,
+		Header:     http.Header{"Content-Type": []string{"application/x-ndjson"}},
+	}, nil
+}
+
+func TestRetryRoundTripper_Stream(t *testing.T) {
+	pr, pw := io.Pipe()
+	mock := &mockStreamTransport{body: pr}
+	clock := NewFakeClock(time.Unix(0, 0))
+
+	rt := NewRoundTripper(mock, RoundTripperOptions{
+		Until:          ".ready",
+		RequestTimeout: 20 * time.Millisecond,
+		Clock:          clock,
+		Strategy:       Exp(),
+		Retrier: NewRetrier(RetryOptions{
+			InitialDelay: time.Millisecond,
+			MaxDelay:     5 * time.Millisecond,
+			MaxAttempts:  5,
+		}),
+	})
+
+	req, _ := http.NewRequestWithContext(context.Background(), http.MethodGet, "http://example.com", nil)
+
+	// the body never ends: the round tripper must not read it
+	resp, err := rt.RoundTrip(req)
+	require.NoError(t, err)
+	require.Equal(t, 1, mock.callCount)
+
+	// the request timeout does not apply to the body of a stream
+	clock.Advance(50 * time.Millisecond)
+	require.NoError(t, mock.ctx.Err())
+	require.Zero(t, clock.Pending())
+
+	go pw.Write([]byte(`{"ready": false}` + "\n"))
+
+	buf := make([]byte, 64)
+	n, err := resp.Body.Read(buf)
+	require.NoError(t, err)
+	require.Equal(t, `{"ready": false}`+"\n", string(buf[:n]))
+
+	require.NoError(t, resp.Body.Close())
+	require.Error(t, mock.ctx.Err())
+}
+
+func TestRetryRoundTripper_Logger(t *testing.T) {
+	retryOn, err := ParseRetryOn("503")
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	mock := &mockStatusTransport{failures: 2, status: http.StatusServiceUnavailable}
+
+	rt := NewRoundTripper(mock, RoundTripperOptions{
+		Until:    ".ready",
+		RetryOn:  retryOn,
+		Logger:   slog.New(slog.NewJSONHandler(&buf, nil)),
+		Strategy: Exp(),
+		Retrier: NewRetrier(RetryOptions{
+			InitialDelay: 10 * time.Millisecond,
+			MaxDelay:     50 * time.Millisecond,
+			MaxAttempts:  5,
+		}),
+	})
+
+	req, _ := http.NewRequestWithContext(context.Background(), http.MethodGet, "http://example.com/ready", nil)
+
+	_, err = rt.RoundTrip(req)
+	require.NoError(t, err)
+
+	type record struct {
+		Level   string   `json:"level"`
+		Msg     string   `json:"msg"`
+		Attempt int      `json:"attempt"`
+		DelayMs float64  `json:"delay_ms"`
+		Method  string   `json:"method"`
+		URL     string   `json:"url"`
+		Status  int      `json:"status"`
+		Bytes   int      `json:"bytes"`
+		Until   *bool    `json:"until"`
+		Error   string   `json:"error"`
+		Latency *float64 `json:"latency_ms"`
+	}
+
+	var got []record
+	dec := json.NewDecoder(&buf)
+	for dec.More() {
+		var el record
+		require.NoError(t, dec.Decode(&el))
+		got = append(got, el)
+	}
+
+	require.Len(t, got, 3)
+	for i, el := range got {
+		require.Equal(t, "attempt", el.Msg)
+		require.Equal(t, i+1, el.Attempt)
+		require.Equal(t, http.MethodGet, el.Method)
+		require.Equal(t, "http://example.com/ready", el.URL)
+		require.NotNil(t, el.Latency)
+	}
+
+	require.Equal(t, "WARN", got[0].Level)
+	require.Equal(t, 503, got[0].Status)
+	require.Equal(t, len("unavailable"), got[0].Bytes)
+	require.Contains(t, got[0].Error, "503")
+	require.Zero(t, got[0].DelayMs)
+	require.GreaterOrEqual(t, got[1].DelayMs, float64(10))
+
+	require.Equal(t, "INFO", got[2].Level)
+	require.Equal(t, 200, got[2].Status)
+	require.NotNil(t, got[2].Until)
+	require.True(t, *got[2].Until)
+	require.Empty(t, got[2].Error)
+}
+
+// mockRetryAfterTransport answers the first request asking to
+// retry 3 seconds later, with a 503 unless another status is given.
+type mockRetryAfterTransport struct {
+	clock     Clock
+	status    int
+	body      string
+	callCount int
+}
+
+func (m *mockRetryAfterTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	m.callCount++
+
+	if m.callCount == 1 {
+		status, body := m.status, m.body
+		if status == 0 {
+			status, body = http.StatusServiceUnavailable, "unavailable"
+		}
+
+		at := m.clock.Now().Add(3 * time.Second).UTC().Format(http.TimeFormat)
+		return &http.Response{
+			StatusCode: status,
+			Body:       io.NopCloser(bytes.NewBufferString(body)),
+			Header: http.Header{
+				"Content-Type": []string{"application/json"},
+				"Retry-After":  []string{at},
+			},
+		}, nil
+	}
+
+	return &http.Response{
+		StatusCode: 200,
+		Body:       io.NopCloser(bytes.NewBufferString(`{"ready": true}`)),
+		Header:     http.Header{"Content-Type": []string{"application/json"}},
+	}, nil
+}
+
+func TestRetryRoundTripper_RetryAfterClock(t *testing.T) {
+	retryOn, err := ParseRetryOn("503")
+	require.NoError(t, err)
+
+	clock := NewFakeClock(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	mock := &mockRetryAfterTransport{clock: clock}
+
+	rt := NewRoundTripper(mock, RoundTripperOptions{
+		Until:    ".ready",
+		RetryOn:  retryOn,
+		Clock:    clock,
+		Strategy: Exp(),
+		Retrier: NewRetrier(RetryOptions{
+			InitialDelay: time.Millisecond,
+			MaxDelay:     time.Minute,
+			MaxAttempts:  3,
+			Clock:        clock,
+		}),
+	})
+
+	req, _ := http.NewRequestWithContext(context.Background(), http.MethodGet, "http://example.com", nil)
+
+	drive(t, clock, func() {
+		_, err = rt.RoundTrip(req)
+	})
+	require.NoError(t, err)
+	require.Equal(t, 2, mock.callCount)
+	require.Equal(t, []time.Duration{3 * time.Second}, clock.Waits())
+}
+
+func TestRetryRoundTripper_RetryAfterUntil(t *testing.T) {
+	tests := []struct {
+		name   string
+		status int
+		body   string
+	}{
+		{name: "busy server", status: http.StatusTooManyRequests, body: `{"message": "slow down"}`},
+		{name: "unavailable server"},
+		{name: "condition not met yet", status: http.StatusAccepted, body: `{"ready": false}`},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			clock := NewFakeClock(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+			mock := &mockRetryAfterTransport{clock: clock, status: tc.status, body: tc.body}
+
+			rt := NewRoundTripper(mock, RoundTripperOptions{
+				Until:    ".ready",
+				Clock:    clock,
+				Strategy: Exp(),
+				Retrier: NewRetrier(RetryOptions{
+					InitialDelay: time.Millisecond,
+					MaxDelay:     time.Minute,
+					MaxAttempts:  3,
+					Clock:        clock,
+				}),
+			})
+
+			req, _ := http.NewRequestWithContext(context.Background(), http.MethodGet, "http://example.com", nil)
+
+			var err error
+			drive(t, clock, func() {
+				_, err = rt.RoundTrip(req)
+			})
+			require.NoError(t, err)
+			require.Equal(t, 2, mock.callCount)
+			require.Equal(t, []time.Duration{3 * time.Second}, clock.Waits())
+		})
+	}
+}
+
+func TestRetryRoundTripper_RequestTimeoutClock(t *testing.T) {
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"ready": %t}`, calls.Add(1) >= 3)
+	}))
+	defer srv.Close()
+
+	clock := NewFakeClock(time.Unix(0, 0))
+	rt := NewRoundTripper(srv.Client().Transport, RoundTripperOptions{
+		Until: ".ready",
+		// only the waits between the attempts must be skipped,
+		// the attempts run in real time
+		RequestTimeout: time.Second,
+		Clock:          clock,
+		Strategy:       Constant(),
+		Retrier: NewRetrier(RetryOptions{
+			InitialDelay: 10 * time.Millisecond,
+			MaxDelay:     time.Minute,
+			MaxAttempts:  5,
+			Clock:        clock,
+		}),
+	})
+
+	req, _ := http.NewRequestWithContext(context.Background(), http.MethodGet, srv.URL, nil)
+
+	var (
+		res *http.Response
+		err error
+	)
+	drive(t, clock, func() {
+		res, err = rt.RoundTrip(req)
+	})
+	require.NoError(t, err)
+	res.Body.Close()
+
+	require.Equal(t, int32(3), calls.Load())
+	require.Equal(t, []time.Duration{10 * time.Millisecond, 10 * time.Millisecond}, clock.Waits())
+}
+
+type mockBodyTransport struct {
+	bodies []string
+}
+
+func (m *mockBodyTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	bin, _ := io.ReadAll(req.Body)
+	m.bodies = append(m.bodies, string(bin))
+
+	status := http.StatusServiceUnavailable
+	if len(m.bodies) >= 3 {
+		status = http.StatusCreated
+	}
+
+	return &http.Response{
+		StatusCode: status,
+		Body:       io.NopCloser(bytes.NewReader(nil)),
+		Header:     http.Header{},
+	}, nil
+}
+
+func TestRetryRoundTripper_RewindsBody(t *testing.T) {
+	retryOn, err := ParseRetryOn("5xx")
+	require.NoError(t, err)
+
+	mock := &mockBodyTransport{}
+	rt := NewRoundTripper(mock, RoundTripperOptions{
+		RetryOn:  retryOn,
+		Strategy: Exp(),
+		Retrier: NewRetrier(RetryOptions{
+			InitialDelay: time.Millisecond,
+			MaxDelay:     time.Millisecond,
+			MaxAttempts:  5,
+		}),
+	})
+
+	payload := `{"name":"resto"}`
+	req, _ := http.NewRequest(http.MethodPost, "http://example.com", bytes.NewBufferString(payload))
+
+	resp, err := rt.RoundTrip(req)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusCreated, resp.StatusCode)
+	require.Equal(t, []string{payload, payload, payload}, mock.bodies)
+}
@@ -1,7 +1,9 @@
 package retry
 
 import (
+	"fmt"
 	"math/rand"
+	"strings"
 	"time"
 )
 
@@ -9,6 +11,83 @@ type Strategy interface {
 	Policy(current, max time.Duration) time.Duration
 }
 
+// Source is the random source of the jittered strategies
+// (*rand.Rand satisfies it).
+type Source interface {
+	Float64() float64
+}
+
+func newSource(c Clock) Source {
+	return rand.New(rand.NewSource(c.Now().UnixNano()))
+}
+
+// Backoff strategy names.
+const (
+	BackoffConstant           = "constant"
+	BackoffLinear             = "linear"
+	BackoffExponential        = "exponential"
+	BackoffFibonacci          = "fibonacci"
+	BackoffDecorrelatedJitter = "decorrelated-jitter"
+	BackoffFullJitter         = "full-jitter"
+)
+
+// NewStrategy returns the Strategy named by opts.Backoff (exponential
+// when empty), growing by opts.Multiplier (the strategy default when zero).
+// The jittered strategies are seeded by opts.Clock.
+//
+// The exponential strategy is jittered too: it adds up to opts.MaxJitter
+// (1s by default, see OptionsFromEnv) to every interval, so it is a pure
+// exponential only when MaxJitter is zero.
+//
+// A multiplier is rejected when the strategy does not use it (constant,
+// fibonacci) or when it would not grow the interval (below 1 for the
+// exponential, decorrelated-jitter and full-jitter strategies).
+func NewStrategy(opts RetryOptions) (Strategy, error) {
+	return newStrategy(opts, newSource(clockOrReal(opts.Clock)))
+}
+
+func newStrategy(opts RetryOptions, src Source) (Strategy, error) {
+	if opts.Multiplier < 0 {
+		return nil, fmt.Errorf("invalid backoff multiplier %g", opts.Multiplier)
+	}
+
+	multiplier := func(def float64) float64 {
+		if opts.Multiplier == 0 {
+			return def
+		}
+		return opts.Multiplier
+	}
+
+	name := strings.ToLower(strings.TrimSpace(opts.Backoff))
+	switch name {
+	case BackoffConstant, BackoffFibonacci:
+		if opts.Multiplier != 0 {
+			return nil, fmt.Errorf("backoff multiplier %g has no effect on the %s backoff", opts.Multiplier, name)
+		}
+	case "", BackoffExponential, BackoffDecorrelatedJitter, BackoffFullJitter:
+		if opts.Multiplier != 0 && opts.Multiplier < 1 {
+			return nil, fmt.Errorf("invalid backoff multiplier %g (must be at least 1)", opts.Multiplier)
+		}
+	}
+
+	switch name {
+	case BackoffConstant:
+		return Constant(), nil
+	case BackoffLinear:
+		return Linear(time.Duration(float64(opts.InitialDelay) * multiplier(1))), nil
+	case "", BackoffExponential:
+		return &jitteredExp{curve: multiplier(2), maxJitter: opts.MaxJitter, rng: src}, nil
+	case BackoffFibonacci:
+		return Fibonacci(), nil
+	case BackoffDecorrelatedJitter:
+		return DecorrelatedJitter(opts.InitialDelay, multiplier(3), src), nil
+	case BackoffFullJitter:
+		return FullJitter(multiplier(2), src), nil
+	default:
+		return nil, fmt.Errorf("unsupported backoff %q (use constant, linear, exponential, fibonacci, decorrelated-jitter or full-jitter)", opts.Backoff)
+	}
+}
+
 func Exp() Strategy {
 	return &expPolicy{curve: 2.0}
 }
@@ -17,10 +96,39 @@ func Jittered(maxJitter time.Duration) Strategy {
 	return &jitteredExp{
 		curve:     2.0,
 		maxJitter: maxJitter,
-		rng:       rand.New(rand.NewSource(time.Now().UnixNano())),
+		rng:       newSource(RealClock()),
 	}
 }
 
+// Constant waits always the same interval.
+func Constant() Strategy {
+	return constantPolicy{}
+}
+
+// Linear increases the interval by step at every attempt.
+func Linear(step time.Duration) Strategy {
+	return &linearPolicy{step: step}
+}
+
+// Fibonacci increases the interval as the Fibonacci
+// sequence does: 1, 1, 2, 3, 5, 8... times the initial one.
+func Fibonacci() Strategy {
+	return &fibonacciPolicy{}
+}
+
+// DecorrelatedJitter picks the next interval at random between base and
+// the current one times multiplier (3 in the "Exponential Backoff And
+// Jitter" AWS article).
+func DecorrelatedJitter(base time.Duration, multiplier float64, src Source) Strategy {
+	return &decorrelatedJitter{base: base, multiplier: multiplier, rng: src}
+}
+
+// FullJitter picks the next interval at random between zero and an
+// exponentially growing ceiling.
+func FullJitter(curve float64, src Source) Strategy {
+	return &fullJitter{curve: curve, rng: src}
+}
+
 type expPolicy struct {
 	curve float64
 }
@@ -36,7 +144,7 @@ func (po *expPolicy) Policy(current, max time.Duration) time.Duration {
 type jitteredExp struct {
 	curve     float64
 	maxJitter time.Duration
-	rng       *rand.Rand
+	rng       Source
 }
 
 func (je *jitteredExp) Policy(current, max time.Duration) time.Duration {
@@ -48,3 +156,69 @@ func (je *jitteredExp) Policy(current, max time.Duration) time.Duration {
 	}
 	return next
 }
+
+type constantPolicy struct{}
+
+func (constantPolicy) Policy(current, max time.Duration) time.Duration {
+	return min(current, max)
+}
+
+type linearPolicy struct {
+	step time.Duration
+}
+
+func (lp *linearPolicy) Policy(current, max time.Duration) time.Duration {
+	return min(current+lp.step, max)
+}
+
+// The strategies below keep state between the calls: a current interval
+// that differs from the last one returned starts a new sequence.
+
+type fibonacciPolicy struct {
+	prev, last time.Duration
+}
+
+func (fp *fibonacciPolicy) Policy(current, max time.Duration) time.Duration {
+	if current != fp.last {
+		fp.prev = 0
+	}
+
+	next := min(current+fp.prev, max)
+	fp.prev, fp.last = current, next
+
+	return next
+}
+
+type decorrelatedJitter struct {
+	base       time.Duration
+	multiplier float64
+	rng        Source
+}
+
+func (dj *decorrelatedJitter) Policy(current, max time.Duration) time.Duration {
+	upper := time.Duration(float64(current) * dj.multiplier)
+	if upper < dj.base {
+		upper = dj.base
+	}
+
+	next := dj.base + time.Duration(dj.rng.Float64()*float64(upper-dj.base))
+	return min(next, max)
+}
+
+type fullJitter struct {
+	curve   float64
+	rng     Source
+	ceiling time.Duration
+	last    time.Duration
+}
+
+func (fj *fullJitter) Policy(current, max time.Duration) time.Duration {
+	if current != fj.last {
+		fj.ceiling = current
+	}
+
+	fj.ceiling = min(time.Duration(float64(fj.ceiling)*fj.curve), max)
+	fj.last = time.Duration(fj.rng.Float64() * float64(fj.ceiling))
+
+	return fj.last
+}
@@ -0,0 +1,139 @@
+package retry
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// fixedSource returns the given values in turn, forever.
+type fixedSource struct {
+	values []float64
+	i      int
+}
+
+func (fs *fixedSource) Float64() float64 {
+	res := fs.values[fs.i%len(fs.values)]
+	fs.i++
+	return res
+}
+
+// intervals returns the waits of n attempts, as computed by the Retrier.
+func intervals(st Strategy, initial, max time.Duration, n int) []time.Duration {
+	res := []time.Duration{initial}
+	for len(res) < n {
+		res = append(res, st.Policy(res[len(res)-1], max))
+	}
+	return res
+}
+
+func TestNewStrategy(t *testing.T) {
+	const ms = time.Millisecond
+
+	tests := []struct {
+		name string
+		opts RetryOptions
+		src  []float64
+		want []time.Duration
+	}{
+		{
+			name: "constant",
+			opts: RetryOptions{Backoff: "constant", InitialDelay: 100 * ms},
+			want: []time.Duration{100 * ms, 100 * ms, 100 * ms, 100 * ms},
+		},
+		{
+			name: "linear",
+			opts: RetryOptions{Backoff: "linear", InitialDelay: 100 * ms},
+			want: []time.Duration{100 * ms, 200 * ms, 300 * ms, 400 * ms, 500 * ms},
+		},
+		{
+			name: "linear with multiplier",
+			opts: RetryOptions{Backoff: "linear", InitialDelay: 100 * ms, Multiplier: 2.5},
+			want: []time.Duration{100 * ms, 350 * ms, 600 * ms, 850 * ms},
+		},
+		{
+			name: "exponential is the default",
+			opts: RetryOptions{InitialDelay: 100 * ms},
+			src:  []float64{0.5},
+			want: []time.Duration{100 * ms, 200 * ms, 400 * ms, 800 * ms, time.Second},
+		},
+		{
+			name: "exponential with jitter and multiplier",
+			opts: RetryOptions{Backoff: "EXPONENTIAL", InitialDelay: 100 * ms, MaxJitter: 100 * ms, Multiplier: 3},
+			src:  []float64{0.5, 0},
+			want: []time.Duration{100 * ms, 350 * ms, time.Second, time.Second},
+		},
+		{
+			name: "fibonacci",
+			opts: RetryOptions{Backoff: "fibonacci", InitialDelay: 100 * ms},
+			want: []time.Duration{100 * ms, 100 * ms, 200 * ms, 300 * ms, 500 * ms, 800 * ms, time.Second},
+		},
+		{
+			name: "decorrelated jitter",
+			opts: RetryOptions{Backoff: "decorrelated-jitter", InitialDelay: 100 * ms},
+			src:  []float64{0.5, 1, 0},
+			// base + rnd * (current*3 - base)
+			want: []time.Duration{100 * ms, 200 * ms, 600 * ms, 100 * ms, 200 * ms},
+		},
+		{
+			name: "full jitter",
+			opts: RetryOptions{Backoff: "full-jitter", InitialDelay: 100 * ms},
+			src:  []float64{0.5, 1, 0.25, 1},
+			// rnd * min(max, initial * 2^n)
+			want: []time.Duration{100 * ms, 100 * ms, 400 * ms, 200 * ms, time.Second},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			src := &fixedSource{values: tc.src}
+			if len(tc.src) == 0 {
+				src.values = []float64{0}
+			}
+
+			st, err := newStrategy(tc.opts, src)
+			require.NoError(t, err)
+
+			got := intervals(st, tc.opts.InitialDelay, time.Second, len(tc.want))
+			require.Equal(t, tc.want, got)
+		})
+	}
+}
+
+func TestNewStrategy_Invalid(t *testing.T) {
+	_, err := NewStrategy(RetryOptions{Backoff: "quadratic"})
+	require.ErrorContains(t, err, `unsupported backoff "quadratic"`)
+
+	_, err = NewStrategy(RetryOptions{Backoff: "linear", Multiplier: -1})
+	require.ErrorContains(t, err, "invalid backoff multiplier")
+
+	for _, name := range []string{BackoffConstant, BackoffFibonacci} {
+		_, err = NewStrategy(RetryOptions{Backoff: name, Multiplier: 2})
+		require.ErrorContains(t, err, "has no effect on the "+name+" backoff")
+	}
+
+	for _, name := range []string{"", BackoffExponential, BackoffDecorrelatedJitter, BackoffFullJitter} {
+		_, err = NewStrategy(RetryOptions{Backoff: name, Multiplier: 0.5})
+		require.ErrorContains(t, err, "must be at least 1", name)
+	}
+
+	_, err = NewStrategy(RetryOptions{Backoff: BackoffLinear, Multiplier: 0.5})
+	require.NoError(t, err)
+}
+
+func TestStrategy_RestartsSequence(t *testing.T) {
+	const ms = time.Millisecond
+
+	for _, name := range []string{BackoffFibonacci, BackoffFullJitter} {
+		t.Run(name, func(t *testing.T) {
+			st, err := newStrategy(RetryOptions{Backoff: name, InitialDelay: 100 * ms}, &fixedSource{values: []float64{1}})
+			require.NoError(t, err)
+
+			// the same strategy shared by two retry loops, one after the other
+			first := intervals(st, 100*ms, time.Second, 4)
+			second := intervals(st, 100*ms, time.Second, 4)
+			require.Equal(t, first, second)
+		})
+	}
+}
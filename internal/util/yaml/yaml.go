@@ -0,0 +1,313 @@
+package yaml
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+
+	goyaml "gopkg.in/yaml.v3"
+)
+
+// ToJSON converts a (possibly multi-document) YAML source into
+// one JSON document for each non empty YAML document.
+//
+// Mapping keys keep the order they have in the YAML source
+// and aliases are resolved.
+func ToJSON(src []byte) ([][]byte, error) {
+	var res [][]byte
+
+	dec := goyaml.NewDecoder(bytes.NewReader(src))
+	for {
+		var doc goyaml.Node
+		err := dec.Decode(&doc)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid YAML: %w", err)
+		}
+
+		if isEmptyDocument(&doc) {
+			continue
+		}
+
+		var buf bytes.Buffer
+		if err := writeJSON(&buf, &doc); err != nil {
+			return nil, err
+		}
+		res = append(res, buf.Bytes())
+	}
+
+	return res, nil
+}
+
+// FromJSON converts a JSON document into YAML,
+// keeping the order of the object keys.
+func FromJSON(src []byte) ([]byte, error) {
+	dec := json.NewDecoder(bytes.NewReader(src))
+	dec.UseNumber()
+
+	node, err := jsonToNode(dec)
+	if err != nil {
+		return nil, fmt.Errorf("invalid JSON: %w", err)
+	}
+
+	if _, err := dec.Token(); !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("invalid JSON: unexpected data after top-level value")
+	}
+
+	var buf bytes.Buffer
+	enc := goyaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(node); err != nil {
+		return nil, err
+	}
+	if err := enc.Close(); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+func jsonToNode(dec *json.Decoder) (*goyaml.Node, error) {
+	tok, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+
+	switch v := tok.(type) {
+	case json.Delim:
+		switch v {
+		case '{':
+			res := &goyaml.Node{Kind: goyaml.MappingNode, Tag: "!!map"}
+			for dec.More() {
+				key, err := dec.Token()
+				if err != nil {
+					return nil, err
+				}
+
+				val, err := jsonToNode(dec)
+				if err != nil {
+					return nil, err
+				}
+
+				res.Content = append(res.Content,
+					&goyaml.Node{Kind: goyaml.ScalarNode, Tag: "!!str", Value: key.(string)},
+					val,
+				)
+			}
+			_, err := dec.Token() // closing '}'
+			return res, err
+
+		case '[':
+			res := &goyaml.Node{Kind: goyaml.SequenceNode, Tag: "!!seq"}
+			for dec.More() {
+				val, err := jsonToNode(dec)
+				if err != nil {
+					return nil, err
+				}
+				res.Content = append(res.Content, val)
+			}
+			_, err := dec.Token() // closing ']'
+			return res, err
+		}
+
+	case string:
+		return &goyaml.Node{Kind: goyaml.ScalarNode, Tag: "!!str", Value: v}, nil
+
+	case json.Number:
+		tag := "!!float"
+		if _, err := v.Int64(); err == nil {
+			tag = "!!int"
+		}
+		return &goyaml.Node{Kind: goyaml.ScalarNode, Tag: tag, Value: v.String()}, nil
+
+	case bool:
+		return &goyaml.Node{Kind: goyaml.ScalarNode, Tag: "!!bool", Value: fmt.Sprint(v)}, nil
+
+	case nil:
+		return &goyaml.Node{Kind: goyaml.ScalarNode, Tag: "!!null", Value: "null"}, nil
+	}
+
+	return nil, fmt.Errorf("unexpected JSON token %v", tok)
+}
+
+// isEmptyDocument reports whether the node is a document with no
+// content, like the one between two consecutive '---' separators.
+func isEmptyDocument(n *goyaml.Node) bool {
+	if n.Kind != goyaml.DocumentNode || len(n.Content) == 0 {
+		return true
+	}
+
+	el := n.Content[0]
+	return el.Kind == goyaml.ScalarNode && el.ShortTag() == "!!null" && el.Value == ""
+}
+
+func writeJSON(buf *bytes.Buffer, n *goyaml.Node) error {
+	switch n.Kind {
+	case goyaml.DocumentNode:
+		return writeJSON(buf, n.Content[0])
+
+	case goyaml.AliasNode:
+		return writeJSON(buf, n.Alias)
+
+	case goyaml.MappingNode:
+		fields, err := mappingFields(n)
+		if err != nil {
+			return err
+		}
+
+		buf.WriteByte('{')
+		for i, el := range fields {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			key, err := json.Marshal(el.key)
+			if err != nil {
+				return err
+			}
+			buf.Write(key)
+			buf.WriteByte(':')
+			if err := writeJSON(buf, el.value); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte('}')
+		return nil
+
+	case goyaml.SequenceNode:
+		buf.WriteByte('[')
+		for i, el := range n.Content {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			if err := writeJSON(buf, el); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte(']')
+		return nil
+
+	case goyaml.ScalarNode:
+		return writeScalar(buf, n)
+	}
+
+	return fmt.Errorf("unsupported YAML node at line %d", n.Line)
+}
+
+type mappingField struct {
+	key   string
+	value *goyaml.Node
+}
+
+// mappingFields returns the fields of the mapping resolving the merge
+// keys (<<): the keys of the mapping override the merged ones and, when
+// a list of mappings is merged, the first one defining a key wins.
+func mappingFields(n *goyaml.Node) ([]mappingField, error) {
+	var (
+		res      []mappingField
+		index    = map[string]int{}
+		explicit = map[string]bool{}
+	)
+
+	add := func(key string, value *goyaml.Node, merged bool) {
+		i, ok := index[key]
+		switch {
+		case !ok:
+			index[key] = len(res)
+			res = append(res, mappingField{key: key, value: value})
+		case !merged && !explicit[key]:
+			res[i].value = value
+		default:
+			return
+		}
+		if !merged {
+			explicit[key] = true
+		}
+	}
+
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		key, value := n.Content[i], n.Content[i+1]
+		if !isMergeKey(key) {
+			add(key.Value, value, false)
+			continue
+		}
+
+		sources := []*goyaml.Node{value}
+		if resolveAlias(value).Kind == goyaml.SequenceNode {
+			sources = resolveAlias(value).Content
+		}
+
+		for _, src := range sources {
+			src = resolveAlias(src)
+			if src.Kind != goyaml.MappingNode {
+				return nil, fmt.Errorf("invalid merge at line %d: a mapping or a list of mappings is expected", src.Line)
+			}
+
+			fields, err := mappingFields(src)
+			if err != nil {
+				return nil, err
+			}
+			for _, el := range fields {
+				add(el.key, el.value, true)
+			}
+		}
+	}
+
+	return res, nil
+}
+
+func isMergeKey(n *goyaml.Node) bool {
+	return n.Kind == goyaml.ScalarNode && n.ShortTag() == "!!merge"
+}
+
+func resolveAlias(n *goyaml.Node) *goyaml.Node {
+	for n.Kind == goyaml.AliasNode {
+		n = n.Alias
+	}
+	return n
+}
+
+func writeScalar(buf *bytes.Buffer, n *goyaml.Node) error {
+	var val any
+
+	switch n.ShortTag() {
+	case "!!null":
+		buf.WriteString("null")
+		return nil
+	case "!!bool":
+		var b bool
+		if err := n.Decode(&b); err != nil {
+			return err
+		}
+		val = b
+	case "!!int":
+		var i int64
+		if err := n.Decode(&i); err != nil {
+			return err
+		}
+		val = i
+	case "!!float":
+		var f float64
+		if err := n.Decode(&f); err != nil {
+			return err
+		}
+		if math.IsInf(f, 0) || math.IsNaN(f) {
+			return fmt.Errorf("value %q at line %d cannot be represented in JSON", n.Value, n.Line)
+		}
+		val = f
+	default:
+		val = n.Value
+	}
+
+	bin, err := json.Marshal(val)
+	if err != nil {
+		return err
+	}
+	buf.Write(bin)
+
+	return nil
+}
@@ -0,0 +1,146 @@
+package yaml
+
+import (
+	"os"
+	"testing"
+)
+
+func TestToJSON(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    []string
+		wantErr bool
+	}{
+		{
+			name:  "keys keep their order",
+			input: "zeta: 1\nalpha: two\nmid: [true, null, 1.5]\n",
+			want:  []string{`{"zeta":1,"alpha":"two","mid":[true,null,1.5]}`},
+		},
+		{
+			name:  "multiple documents",
+			input: "---\na: 1\n---\n---\nb: '2'\n",
+			want:  []string{`{"a":1}`, `{"b":"2"}`},
+		},
+		{
+			name:  "aliases are resolved",
+			input: "base: &b {x: 1}\ncopy: *b\n",
+			want:  []string{`{"base":{"x":1},"copy":{"x":1}}`},
+		},
+		{
+			name:  "merge keys are resolved",
+			input: "base: &b {x: 1, y: 2}\nitem:\n  <<: *b\n  y: 3\n  z: 4\n",
+			want:  []string{`{"base":{"x":1,"y":2},"item":{"x":1,"y":3,"z":4}}`},
+		},
+		{
+			name:  "explicit keys win over merged ones",
+			input: "a: &a {x: 1}\nb: &b {x: 2, y: 2}\nitem:\n  x: 0\n  <<: [*a, *b]\n",
+			want:  []string{`{"a":{"x":1},"b":{"x":2,"y":2},"item":{"x":0,"y":2}}`},
+		},
+		{
+			name:  "quoted merge key is a plain key",
+			input: "'<<': 1\n",
+			want:  []string{`{"\u003c\u003c":1}`},
+		},
+		{
+			name:    "merge of a scalar",
+			input:   "item:\n  <<: 1\n",
+			wantErr: true,
+		},
+		{
+			name:  "quoted numbers stay strings",
+			input: "port: \"8080\"\n",
+			want:  []string{`{"port":"8080"}`},
+		},
+		{
+			name:    "invalid yaml",
+			input:   "a: [1, 2\n",
+			wantErr: true,
+		},
+		{
+			name:    "infinity is not json",
+			input:   "a: .inf\n",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ToJSON([]byte(tt.input))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("unexpected error status: got err=%v, wantErr=%v", err, tt.wantErr)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %d documents, want %d", len(got), len(tt.want))
+			}
+			for i := range got {
+				if string(got[i]) != tt.want[i] {
+					t.Errorf("document %d: got %s, want %s", i, got[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestToJSONPod(t *testing.T) {
+	src, err := os.ReadFile("../../../testdata/delayed-pod.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := ToJSON(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(got) != 1 {
+		t.Fatalf("got %d documents, want 1", len(got))
+	}
+}
+
+func TestFromJSON(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    string
+		wantErr bool
+	}{
+		{
+			name:  "keys keep their order",
+			input: `{"zeta": 1, "alpha": {"b": [1.5, true, null], "a": "x"}}`,
+			want:  "zeta: 1\nalpha:\n  b:\n    - 1.5\n    - true\n    - null\n  a: x\n",
+		},
+		{
+			name:  "strings looking like other types are quoted",
+			input: `{"port": "8080", "flag": "true", "empty": ""}`,
+			want:  "port: \"8080\"\nflag: \"true\"\nempty: \"\"\n",
+		},
+		{
+			name:  "top level array",
+			input: `[{"a": 1}, "b"]`,
+			want:  "- a: 1\n- b\n",
+		},
+		{
+			name:    "invalid json",
+			input:   `{"a": }`,
+			wantErr: true,
+		},
+		{
+			name:    "trailing data",
+			input:   `{"a": 1} {"b": 2}`,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := FromJSON([]byte(tt.input))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("unexpected error status: got err=%v, wantErr=%v", err, tt.wantErr)
+			}
+			if string(got) != tt.want {
+				t.Errorf("got:\n%s\nwant:\n%s", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,229 @@
+package workflow
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"maps"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/lucasepe/resto/internal/env"
+	"github.com/lucasepe/resto/internal/restclient"
+	"github.com/lucasepe/resto/internal/util/jq"
+	"github.com/lucasepe/resto/internal/util/retry"
+)
+
+// Runner executes the steps of a workflow sequentially,
+// stopping at the first failing step.
+type Runner struct {
+	// Client is the base HTTP client (TLS, proxy, authentication);
+	// each step wraps its transport with the retry round tripper.
+	Client *http.Client
+	// ServerURL is used as base for the steps with a relative url.
+	ServerURL    string
+	RetryOptions retry.RetryOptions
+	RetryOn      retry.RetryOn
+	// Vars are merged over the workflow vars.
+	Vars map[string]string
+	// Strict makes references to undefined variables fail the step.
+	Strict  bool
+	Streams restclient.IOStreams
+	// Logger, if set, receives one record per attempt.
+	Logger *slog.Logger
+}
+
+// Result is the outcome of a single step.
+type Result struct {
+	Step    string
+	Method  string
+	URL     string
+	Elapsed time.Duration
+	Err     error
+	Skipped bool
+}
+
+// Run executes the workflow and returns the result of every step.
+// The returned error is the one of the first failing step, if any.
+func (r *Runner) Run(ctx context.Context, wf *Workflow) ([]Result, error) {
+	vars := map[string]string{}
+	maps.Copy(vars, wf.Vars)
+	maps.Copy(vars, r.Vars)
+
+	res := make([]Result, 0, len(wf.Steps))
+
+	var failed error
+	for _, st := range wf.Steps {
+		if failed != nil {
+			res = append(res, Result{Step: st.Name, Method: method(st), URL: st.URL, Skipped: true})
+			continue
+		}
+
+		start := time.Now()
+		uri, err := r.runStep(ctx, st, vars)
+		res = append(res, Result{
+			Step:    st.Name,
+			Method:  method(st),
+			URL:     uri,
+			Elapsed: time.Since(start),
+			Err:     err,
+		})
+
+		if err != nil {
+			failed = fmt.Errorf("step %q failed: %w", st.Name, err)
+		}
+	}
+
+	return res, failed
+}
+
+func (r *Runner) runStep(ctx context.Context, st Step, vars map[string]string) (string, error) {
+	uri, err := env.Expand(st.URL, vars, r.Strict)
+	if err != nil {
+		return st.URL, err
+	}
+
+	headers := st.headerLines()
+	for i, el := range headers {
+		headers[i], err = env.Expand(el, vars, r.Strict)
+		if err != nil {
+			return uri, err
+		}
+	}
+
+	body, err := env.Expand(st.Body, vars, r.Strict)
+	if err != nil {
+		return uri, err
+	}
+
+	pollURL, err := env.Expand(st.PollURL, vars, r.Strict)
+	if err != nil {
+		return uri, err
+	}
+
+	until, err := env.Expand(st.Until, vars, r.Strict)
+	if err != nil {
+		return uri, err
+	}
+
+	failIf, err := env.Expand(st.FailIf, vars, r.Strict)
+	if err != nil {
+		return uri, err
+	}
+
+	reqOpts := restclient.RequestOptions{
+		BaseURL: uri,
+		Method:  method(st),
+		Headers: headers,
+		Until:   until,
+		FailIf:  failIf,
+		PollURL: pollURL,
+	}
+
+	if u, err := url.Parse(uri); err == nil && !u.IsAbs() {
+		reqOpts.BaseURL = r.ServerURL
+		reqOpts.Path = uri
+	}
+
+	strategy, err := retry.NewStrategy(r.RetryOptions)
+	if err != nil {
+		return uri, err
+	}
+
+	cli := *r.Client
+	cli.Transport = retry.NewRoundTripper(r.Client.Transport, retry.RoundTripperOptions{
+		Until:          until,
+		FailIf:         failIf,
+		RetryOn:        r.RetryOn,
+		RequestTimeout: r.RetryOptions.RequestTimeout,
+		Logger:         r.Logger,
+		Clock:          r.RetryOptions.Clock,
+		Strategy:       strategy,
+		Retrier:        retry.NewRetrier(r.RetryOptions),
+	})
+
+	var out bytes.Buffer
+	streams := restclient.IOStreams{
+		Out: &out,
+		Err: r.Streams.Err,
+	}
+	if body != "" {
+		streams.In = strings.NewReader(body)
+	}
+
+	err = restclient.New(reqOpts).Do(ctx, &cli, streams)
+	if err != nil {
+		return uri, err
+	}
+
+	if r.Streams.Out != nil {
+		if _, err := r.Streams.Out.Write(out.Bytes()); err != nil {
+			return uri, err
+		}
+	}
+
+	for name, expr := range st.Capture {
+		expr, err := env.Expand(expr, vars, r.Strict)
+		if err != nil {
+			return uri, fmt.Errorf("unable to capture %q: %w", name, err)
+		}
+
+		val, err := capture(out.Bytes(), expr)
+		if err != nil {
+			return uri, fmt.Errorf("unable to capture %q: %w", name, err)
+		}
+		vars[name] = val
+	}
+
+	return uri, nil
+}
+
+// capture evaluates the JQ expression on the JSON response and returns
+// the first result: strings as is, any other value encoded as JSON.
+func capture(body []byte, expr string) (string, error) {
+	res, err := jq.Eval(body, expr)
+	if err != nil {
+		return "", err
+	}
+
+	if len(res) == 0 || res[0] == nil {
+		return "", fmt.Errorf("expression %q produced no value", expr)
+	}
+
+	if s, ok := res[0].(string); ok {
+		return s, nil
+	}
+
+	bin, err := json.Marshal(res[0])
+	if err != nil {
+		return "", err
+	}
+
+	return string(bin), nil
+}
+
+func method(st Step) string {
+	if st.Method == "" {
+		return http.MethodGet
+	}
+	return strings.ToUpper(st.Method)
+}
+
+// WriteSummary prints a pass/fail line for every step.
+func WriteSummary(wri io.Writer, results []Result) {
+	for _, el := range results {
+		switch {
+		case el.Skipped:
+			fmt.Fprintf(wri, "- %s: %s %s (skipped)\n", el.Step, el.Method, el.URL)
+		case el.Err != nil:
+			fmt.Fprintf(wri, "✘ %s: %s %s (%s): %v\n", el.Step, el.Method, el.URL, el.Elapsed.Round(time.Millisecond), el.Err)
+		default:
+			fmt.Fprintf(wri, "✔ %s: %s %s (%s)\n", el.Step, el.Method, el.URL, el.Elapsed.Round(time.Millisecond))
+		}
+	}
+}
@@ -0,0 +1,104 @@
+package workflow
+
+import (
+	"fmt"
+	"os"
+	"sort"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Workflow is a sequence of requests executed one after the other.
+//
+// Example:
+//
+//	vars:
+//	  NS: default
+//	steps:
+//	  - name: create
+//	    method: POST
+//	    url: /api/v1/namespaces/${NS}/pods
+//	    headers:
+//	      Content-Type: application/json
+//	    body: |
+//	      {"apiVersion": "v1", "kind": "Pod", ...}
+//	    capture:
+//	      POD: .metadata.name
+//	  - name: wait
+//	    url: /api/v1/namespaces/${NS}/pods/${POD}/status
+//	    until: .status.phase == "Running"
+//	  - name: delete
+//	    method: DELETE
+//	    url: /api/v1/namespaces/${NS}/pods/${POD}
+type Workflow struct {
+	// Vars are the initial variables available to every step.
+	Vars  map[string]string `yaml:"vars"`
+	Steps []Step            `yaml:"steps"`
+}
+
+// Step is a single request of a workflow.
+//
+// URL, header values, body, poll-url and the JQ expressions (until,
+// fail-if and capture) can reference variables as ${NAME}: workflow vars,
+// --var flags, the environment and the values captured by the previous steps.
+type Step struct {
+	Name    string            `yaml:"name"`
+	Method  string            `yaml:"method"`
+	URL     string            `yaml:"url"`
+	Headers map[string]string `yaml:"headers"`
+	Body    string            `yaml:"body"`
+	// Until is the JQ expression the response must satisfy;
+	// the request is retried until it evaluates to true.
+	// Requests other than GET are sent once and then polled.
+	Until string `yaml:"until"`
+	// PollURL is where Until is polled after a request other than GET;
+	// the Location header of the response, or the url, when empty.
+	PollURL string `yaml:"poll-url"`
+	// FailIf is the JQ expression that, when true, fails the step immediately.
+	FailIf string `yaml:"fail-if"`
+	// Capture maps variable names to JQ expressions evaluated on the
+	// JSON response; the first result is stored for the next steps.
+	Capture map[string]string `yaml:"capture"`
+}
+
+// Load reads and validates the workflow definition from the given file.
+func Load(filename string) (*Workflow, error) {
+	src, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	var res Workflow
+	if err := yaml.Unmarshal(src, &res); err != nil {
+		return nil, fmt.Errorf("unable to parse workflow %q: %w", filename, err)
+	}
+
+	if len(res.Steps) == 0 {
+		return nil, fmt.Errorf("workflow %q has no steps", filename)
+	}
+
+	for i := range res.Steps {
+		st := &res.Steps[i]
+		if st.URL == "" {
+			return nil, fmt.Errorf("workflow %q: step %d has no url", filename, i+1)
+		}
+		if st.PollURL != "" && st.Until == "" {
+			return nil, fmt.Errorf("workflow %q: step %d has a poll-url but no until", filename, i+1)
+		}
+		if st.Name == "" {
+			st.Name = fmt.Sprintf("step-%d", i+1)
+		}
+	}
+
+	return &res, nil
+}
+
+// headerLines returns the step headers as sorted "Key: Value" strings.
+func (st *Step) headerLines() []string {
+	res := make([]string, 0, len(st.Headers))
+	for k, v := range st.Headers {
+		res = append(res, k+": "+v)
+	}
+	sort.Strings(res)
+	return res
+}
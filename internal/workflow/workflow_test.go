@@ -0,0 +1,154 @@
+package workflow
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/lucasepe/resto/internal/restclient"
+	"github.com/lucasepe/resto/internal/util/retry"
+)
+
+const testWorkflow = `vars:
+  NS: default
+  FIELD: name
+  PHASE: Running
+steps:
+  - name: create
+    method: post
+    url: /namespaces/${NS}/pods
+    headers:
+      Content-Type: application/json
+    body: '{"name": "${NAME}"}'
+    capture:
+      POD: .metadata.${FIELD}
+  - name: wait
+    url: /namespaces/${NS}/pods/${POD}
+    until: .status.phase == "${PHASE}"
+  - name: delete
+    method: DELETE
+    url: /namespaces/${NS}/pods/${POD}
+`
+
+func TestRunner(t *testing.T) {
+	var (
+		mu    sync.Mutex
+		calls []string
+		polls int
+	)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+
+		mu.Lock()
+		defer mu.Unlock()
+		calls = append(calls, r.Method+" "+r.URL.Path)
+
+		w.Header().Set("Content-Type", "application/json")
+		switch r.Method {
+		case http.MethodPost:
+			if string(body) != `{"name": "demo"}` {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			io.WriteString(w, `{"metadata": {"name": "demo-123"}}`)
+		case http.MethodGet:
+			polls++
+			phase := "Pending"
+			if polls > 2 {
+				phase = "Running"
+			}
+			io.WriteString(w, `{"status": {"phase": "`+phase+`"}}`)
+		default:
+			io.WriteString(w, `{}`)
+		}
+	}))
+	defer srv.Close()
+
+	filename := filepath.Join(t.TempDir(), "workflow.yaml")
+	if err := os.WriteFile(filename, []byte(testWorkflow), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	wf, err := Load(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var out bytes.Buffer
+	runner := &Runner{
+		Client:    srv.Client(),
+		ServerURL: srv.URL,
+		RetryOptions: retry.RetryOptions{
+			InitialDelay: time.Millisecond,
+			MaxDelay:     5 * time.Millisecond,
+			MaxAttempts:  5,
+		},
+		Vars:    map[string]string{"NAME": "demo"},
+		Strict:  true,
+		Streams: restclient.IOStreams{Out: &out, Err: io.Discard},
+	}
+
+	results, err := runner.Run(context.Background(), wf)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := []string{
+		"POST /namespaces/default/pods",
+		"GET /namespaces/default/pods/demo-123",
+		"GET /namespaces/default/pods/demo-123",
+		"GET /namespaces/default/pods/demo-123",
+		"DELETE /namespaces/default/pods/demo-123",
+	}
+	if strings.Join(calls, "\n") != strings.Join(want, "\n") {
+		t.Errorf("got calls:\n%s\nwant:\n%s", strings.Join(calls, "\n"), strings.Join(want, "\n"))
+	}
+
+	if len(results) != 3 {
+		t.Fatalf("expected 3 results, got %d", len(results))
+	}
+
+	var summary bytes.Buffer
+	WriteSummary(&summary, results)
+	if strings.Count(summary.String(), "✔") != 3 {
+		t.Errorf("unexpected summary:\n%s", summary.String())
+	}
+}
+
+func TestRunnerStopsAtFirstFailure(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer srv.Close()
+
+	wf := &Workflow{
+		Steps: []Step{
+			{Name: "first", URL: srv.URL + "/missing"},
+			{Name: "second", URL: srv.URL + "/never"},
+		},
+	}
+
+	runner := &Runner{
+		Client:       srv.Client(),
+		RetryOptions: retry.RetryOptions{MaxAttempts: 1},
+		Streams:      restclient.IOStreams{Out: io.Discard, Err: io.Discard},
+	}
+
+	results, err := runner.Run(context.Background(), wf)
+	if err == nil {
+		t.Fatal("expected error")
+	}
+
+	if results[0].Err == nil || !results[1].Skipped {
+		t.Errorf("unexpected results: %+v", results)
+	}
+}
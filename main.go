@@ -21,6 +21,6 @@ func main() {
 
 	if err := cmd.Run(ctx); err != nil {
 		fmt.Fprintln(os.Stderr, err)
-		os.Exit(1)
+		os.Exit(cmd.ExitCode(err))
 	}
 }